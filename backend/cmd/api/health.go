@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth-go/internal/config"
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+	"auth-go/internal/health"
+	"auth-go/internal/mail"
+	"auth-go/internal/server"
+	"auth-go/internal/service"
+	"auth-go/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// SMTP check modes of HEALTH_SMTP_CHECK
+const (
+	smtpCheckOff     = "off"
+	smtpCheckReport  = "report"
+	smtpCheckRequire = "require"
+)
+
+// newHealthChecker checks the dependencies the API cannot serve without:
+// the database, its schema and the token signing key. SMTP is checked when
+// smtp is set and HEALTH_SMTP_CHECK asks for it; emails wait in the outbox
+// while it is down, so it only fails readiness in "require" mode. With
+// certs, a certificate close to expiry degrades the report. Permanently
+// failed outbox messages degrade it too, they need someone to look at them.
+func newHealthChecker(cfg *config.Config, db *gorm.DB, smtp *mail.SMTPTransport, certs *server.CertReloader, outbox service.OutboxWorker) (*health.Checker, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []health.Check{
+		{
+			Name:     "database",
+			Critical: true,
+			Run: func(ctx context.Context) (interface{}, error) {
+				if err := sqlDB.PingContext(ctx); err != nil {
+					return nil, err
+				}
+				stats := sqlDB.Stats()
+				return map[string]interface{}{
+					"driver":           db.Dialector.Name(),
+					"open_connections": stats.OpenConnections,
+					"in_use":           stats.InUse,
+					"idle":             stats.Idle,
+					"wait_count":       stats.WaitCount,
+				}, nil
+			},
+		},
+		{
+			Name:     "migrations",
+			Critical: true,
+			Run: func(ctx context.Context) (interface{}, error) {
+				statuses, err := migrator.WithContext(ctx).Status()
+				if err != nil {
+					return nil, err
+				}
+				applied, pending := 0, 0
+				var modified []string
+				for _, status := range statuses {
+					if !status.Applied {
+						pending++
+						continue
+					}
+					applied++
+					if status.ChecksumMismatch {
+						modified = append(modified, fmt.Sprintf("%d_%s", status.Version, status.Name))
+					}
+				}
+				details := map[string]interface{}{"applied": applied, "pending": pending}
+				if len(modified) > 0 {
+					details["modified_after_apply"] = modified
+				}
+				// Code running against an older schema fails in surprising ways
+				if pending > 0 {
+					return details, fmt.Errorf("%d migrations are pending, run cmd/migrate up", pending)
+				}
+				return details, nil
+			},
+		},
+		{
+			Name: "outbox",
+			Run: func(ctx context.Context) (interface{}, error) {
+				stats, err := outbox.Stats(ctx)
+				if err != nil {
+					return nil, err
+				}
+				if failed := stats[domain.OutboxStatusFailed]; failed > 0 {
+					return stats, fmt.Errorf("%d outbox messages failed permanently", failed)
+				}
+				return stats, nil
+			},
+		},
+		{
+			Name:     "signing_key",
+			Critical: true,
+			Run: func(ctx context.Context) (interface{}, error) {
+				if cfg.JWTSecret == "" {
+					return nil, errors.New("JWT_SECRET is not set")
+				}
+				token, err := utils.GenerateToken(0, "", cfg.JWTSecret, time.Minute.String())
+				if err != nil {
+					return nil, fmt.Errorf("signing a token failed: %w", err)
+				}
+				if _, err := utils.ValidateToken(token, cfg.JWTSecret); err != nil {
+					return nil, fmt.Errorf("validating a token failed: %w", err)
+				}
+				return map[string]interface{}{"algorithm": "HS256", "token_lifetime": cfg.JWTExpiredIn}, nil
+			},
+		},
+	}
+
+	if smtp != nil && cfg.HealthSMTPCheck != smtpCheckOff {
+		checks = append(checks, health.Check{
+			Name:     "smtp",
+			Critical: cfg.HealthSMTPCheck == smtpCheckRequire,
+			Timeout:  5 * time.Second,
+			Run: func(ctx context.Context) (interface{}, error) {
+				return map[string]interface{}{"host": cfg.SMTPHost, "port": cfg.SMTPPort}, smtp.Ping(ctx)
+			},
+		})
+	}
+
+	if certs != nil {
+		checks = append(checks, health.Check{
+			Name: "tls_certificate",
+			Run: func(ctx context.Context) (interface{}, error) {
+				notAfter := certs.NotAfter()
+				remaining := time.Until(notAfter)
+				details := map[string]interface{}{"not_after": notAfter, "expires_in_days": int(remaining.Hours() / 24)}
+				// Renewals normally happen weeks ahead, a week left means they fail
+				if remaining < 7*24*time.Hour {
+					return details, errors.New("the certificate expires within a week")
+				}
+				return details, nil
+			},
+		})
+	}
+
+	return health.NewChecker(time.Second, checks...), nil
+}
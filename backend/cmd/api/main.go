@@ -1,23 +1,60 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"auth-go/internal/config"
 	"auth-go/internal/database"
+	"auth-go/internal/domain"
 	"auth-go/internal/handler"
+	"auth-go/internal/i18n"
+	"auth-go/internal/logger"
+	"auth-go/internal/mail"
+	"auth-go/internal/metrics"
 	"auth-go/internal/middleware"
+	"auth-go/internal/openapi"
 	"auth-go/internal/repository"
+	"auth-go/internal/server"
 	"auth-go/internal/service"
+	"auth-go/internal/tracing"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
 	// 1. Load Config
-	cfg, err := config.LoadConfig()
+	configOpts := config.Options{Args: os.Args[1:]}
+	cfg, err := config.Load(configOpts)
+	if config.IsHelp(err) {
+		return
+	}
+	if err != nil {
+		fatal("Failed to load config", err)
+	}
+	configStore := config.NewStore(cfg, configOpts)
+	if err := logger.Setup(logger.Options{Format: cfg.LogFormat, Level: cfg.LogLevel, Levels: cfg.LogLevels}); err != nil {
+		fatal("Failed to set up logging", err)
+	}
+
+	tracer, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPProtocol: cfg.TracingOTLPProtocol,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		SampleRatio:  cfg.TracingSampleRatio,
+		ServiceName:  cfg.TracingServiceName,
+		Environment:  cfg.GinMode,
+	})
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("Failed to set up tracing", err)
 	}
 
 	// 2. Connect Database
@@ -25,52 +62,320 @@ func main() {
 
 	// 3. Init Repositories
 	userRepo := repository.NewUserRepository(db)
-	resetRepo := repository.NewPasswordResetRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	exportJobRepo := repository.NewExportJobRepository(db)
+	txManager := repository.NewTxManager(db)
 
 	// 4. Init Services
-	emailService := service.NewEmailService(cfg)
-	authService := service.NewAuthService(userRepo, resetRepo, emailService, cfg)
-	userService := service.NewUserService(userRepo)
+	renderer, err := newRenderer(cfg)
+	if err != nil {
+		fatal("Failed to load email templates", err)
+	}
+	var catalog atomic.Pointer[i18n.Catalog]
+	initialCatalog, err := newCatalog(cfg)
+	if err != nil {
+		fatal("Failed to load message catalogs", err)
+	}
+	catalog.Store(initialCatalog)
+	smtpIdleTimeout, _ := time.ParseDuration(cfg.SMTPIdleTimeout)
+	var mailTransport mail.Transport
+	mailTransport, err = mail.NewTransport(mail.TransportOptions{
+		Kind:    cfg.MailTransport,
+		FileDir: cfg.MailFileDir,
+		SMTP: mail.SMTPOptions{
+			Host:        cfg.SMTPHost,
+			Port:        cfg.SMTPPort,
+			Username:    cfg.SMTPEmail,
+			Password:    cfg.SMTPPassword,
+			TLSMode:     cfg.SMTPTLSMode,
+			PoolSize:    cfg.SMTPPoolSize,
+			IdleTimeout: smtpIdleTimeout,
+		},
+	})
+	if err != nil {
+		fatal("Failed to init mail transport", err)
+	}
+
+	// Health checks reach the SMTP server itself, not a capturing wrapper
+	smtpTransport, _ := mailTransport.(*mail.SMTPTransport)
+
+	// Capture outgoing mail for /_dev/mail outside release mode
+	var mailCapture *mail.CaptureTransport
+	if cfg.GinMode != "release" && cfg.DevMailCapture != "off" {
+		inner := mailTransport
+		if cfg.DevMailCapture == "only" {
+			inner = nil
+			smtpTransport = nil
+		}
+		mailCapture = mail.NewCaptureTransport(inner, 100)
+		mailTransport = mailCapture
+	}
+	emailService := service.NewEmailService(cfg, renderer, mailTransport)
+	authService := service.NewAuthService(userRepo, txManager, cfg)
+	userService := service.NewUserService(userRepo, cfg)
+	userImportService := service.NewUserImportService(userRepo, txManager, cfg)
+	userExportService := service.NewUserExportService(userRepo, exportJobRepo, cfg)
+	userExportService.Start()
+
+	outboxWorker := service.NewOutboxWorker(outboxRepo, emailService, cfg)
+	outboxWorker.Start()
+	if err := metrics.RegisterOutbox(outboxWorker.Stats, domain.OutboxStatuses...); err != nil {
+		fatal("Failed to register outbox metrics", err)
+	}
 
 	// 5. Init Handlers
 	authHandler := handler.NewAuthHandler(authService)
 	userHandler := handler.NewUserHandler(userService)
+	userImportHandler := handler.NewUserImportHandler(userImportService)
+	userExportHandler := handler.NewUserExportHandler(userExportService, cfg.ExportSyncMaxRows)
+	configHandler := handler.NewConfigHandler(configStore)
+	cspReportHandler := handler.NewCSPReportHandler()
+	var certs *server.CertReloader
+	if cfg.TLSCertFile != "" {
+		if certs, err = server.NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			fatal("Failed to load the TLS certificate", err)
+		}
+	}
+	healthChecker, err := newHealthChecker(cfg, db, smtpTransport, certs, outboxWorker)
+	if err != nil {
+		fatal("Failed to set up health checks", err)
+	}
+	healthHandler := handler.NewHealthHandler(healthChecker)
+	apiDoc := newOpenAPIDocument()
+	openAPIHandler, err := handler.NewOpenAPIHandler(apiDoc)
+	if err != nil {
+		fatal("Failed to encode the OpenAPI document", err)
+	}
+	apiValidator, err := openapi.NewValidator(apiDoc)
+	if err != nil {
+		fatal("Failed to compile the OpenAPI schemas", err)
+	}
 
 	// 6. Init Router
-	if cfg.GinMode == "release" {
-		gin.SetMode(gin.ReleaseMode)
+	gin.SetMode(cfg.GinMode)
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		slog.Debug("Route registered", "method", method, "path", path, "handler", handler)
+	}
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		fatal("Failed to set trusted proxies", err)
 	}
-	r := gin.Default()
 
 	// 7. Setup Middleware
-	r.Use(middleware.CORSMiddleware())
-	r.Use(middleware.SecurityHeadersMiddleware())
+	r.Use(middleware.RequestIDMiddleware(), middleware.LocaleMiddleware(catalog.Load), middleware.TracingMiddleware(), middleware.LoggerMiddleware("/healthz", "/readyz"), middleware.RecoveryMiddleware(), middleware.MetricsMiddleware())
+	r.Use(middleware.BodyLimitMiddleware(cfg.MaxBodyBytes))
+	policies, err := newHTTPPolicies(cfg)
+	if err != nil {
+		fatal("Failed to build HTTP policies", err)
+	}
+	r.Use(middleware.CORSMiddleware(policies.cors))
+	r.Use(middleware.SecurityHeadersMiddleware(policies.security))
+	r.Use(middleware.OpenAPIValidationMiddleware(apiValidator, func() bool {
+		return configStore.Current().Feature(config.FeatureOpenAPIValidation)
+	}))
 
 	// 8. Define Routes
-	api := r.Group("/api")
-	{
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
-			auth.POST("/forgot-password", authHandler.ForgotPassword)
-			auth.POST("/reset-password", authHandler.ResetPassword)
-
-			// Protected Auth Route (e.g., Get Current User)
-			auth.GET("/me", middleware.AuthMiddleware(cfg), userHandler.GetProfile)
+	routes := &apiRoutes{
+		cfg:         cfg,
+		configStore: configStore,
+		userRepo:    userRepo,
+		auth:        authHandler,
+		users:       userHandler,
+		userImport:  userImportHandler,
+		userExport:  userExportHandler,
+		config:      configHandler,
+		cspReport:   cspReportHandler,
+		health:      healthHandler,
+		openAPI:     openAPIHandler,
+	}
+	if mailCapture != nil {
+		routes.devMail = handler.NewDevMailHandler(mailCapture)
+		slog.Info("Dev mail catcher available", "url", "http://localhost:"+cfg.Port+"/_dev/mail")
+	}
+
+	// Metrics go on their own listener when possible, otherwise behind a token
+	serverOpts := newServerOptions(cfg)
+	serverOpts.Certs = certs
+	metricsHandler := metrics.Handler(func() string { return configStore.Current().MetricsToken })
+	var metricsServer *http.Server
+	switch {
+	case cfg.MetricsAddr != "":
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler)
+		metricsOpts := serverOpts
+		metricsOpts.Addr, metricsOpts.Certs = cfg.MetricsAddr, nil
+		metricsServer = server.New(mux, metricsOpts)
+		go func() {
+			slog.Info("Metrics listener running", "addr", cfg.MetricsAddr)
+			if err := server.ListenAndServe(metricsServer); err != nil {
+				fatal("Failed to run metrics listener", err)
+			}
+		}()
+	case cfg.MetricsToken != "":
+		routes.metrics = metricsHandler
+	default:
+		slog.Info("Metrics endpoint disabled, set METRICS_ADDR or METRICS_TOKEN to enable it")
+	}
+	routes.register(r)
+
+	// 9. Reload runtime settings on SIGHUP or when the config file changes
+	configStore.OnReload(func(old *config.Config, next *config.Config) (func(), error) {
+		// Templates are checked even when unchanged, they may have been edited
+		nextRenderer, err := newRenderer(next)
+		if err != nil {
+			return nil, err
+		}
+		return func() { renderer.Swap(nextRenderer) }, nil
+	})
+	configStore.OnReload(func(old *config.Config, next *config.Config) (func(), error) {
+		nextCatalog, err := newCatalog(next)
+		if err != nil {
+			return nil, err
+		}
+		return func() { catalog.Store(nextCatalog) }, nil
+	})
+	configStore.OnReload(func(old *config.Config, next *config.Config) (func(), error) {
+		if err := logger.CheckLevels(next.LogLevel, next.LogLevels); err != nil {
+			return nil, err
+		}
+		return func() { logger.SetLevels(next.LogLevel, next.LogLevels) }, nil
+	})
+	configStore.OnReload(func(old *config.Config, next *config.Config) (func(), error) {
+		return policies.prepare(next)
+	})
+	configStore.OnReload(func(old *config.Config, next *config.Config) (func(), error) {
+		// /metrics stays on the API port until restart, so it keeps needing a token
+		if next.MetricsAddr == "" && old.MetricsToken != "" && next.MetricsToken == "" {
+			return nil, errors.New("METRICS_TOKEN cannot be removed while /metrics is served on the API port")
 		}
+		return nil, nil
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if err := configStore.Watch(watchCtx); err != nil {
+		slog.Warn("Reloading the config on file changes is disabled", "error", err)
+	}
+	if certs != nil {
+		// SIGHUP reloads the certificate too, for systems without file events
+		configStore.OnReload(func(old *config.Config, next *config.Config) (func(), error) {
+			return certs.Prepare()
+		})
+		if err := certs.Watch(watchCtx); err != nil {
+			slog.Warn("Reloading the TLS certificate on file changes is disabled", "error", err)
+		}
+	}
+
+	// 10. Start Server
+	srv := server.New(r, serverOpts)
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server running", "port", cfg.Port, "tls", certs != nil)
+		serverErr <- server.ListenAndServe(srv)
+	}()
 
-		users := api.Group("/users")
-		users.Use(middleware.AuthMiddleware(cfg))
-		{
-			users.GET("", userHandler.GetAllUsers)
-			users.GET("/profile", userHandler.GetProfile)
+	// 11. Drain requests and background workers on shutdown
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-signals.Done():
+	case err := <-serverErr:
+		fatal("Failed to run server", err)
+	}
+	// A second signal stops the API at once
+	stopSignals()
+
+	// Fail readiness first, then give load balancers time to notice
+	healthChecker.ShutDown()
+	slog.Info("Shutting down")
+	if delay, _ := time.ParseDuration(cfg.ShutdownDelay); delay > 0 {
+		slog.Info("Waiting for load balancers to stop routing requests", "delay", delay.String())
+		time.Sleep(delay)
+	}
+	shutdownTimeout, _ := time.ParseDuration(cfg.ShutdownTimeout)
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Requests go first, they may still enqueue emails for the outbox
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Draining requests failed, closing remaining connections", "error", err)
+		srv.Close()
+	}
+	if err := outboxWorker.Shutdown(ctx); err != nil {
+		slog.Error("Stopping the outbox worker failed", "error", err)
+	}
+	if err := userExportService.Shutdown(ctx); err != nil {
+		slog.Error("Stopping export jobs failed", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			slog.Error("Stopping the metrics listener failed", "error", err)
 		}
 	}
+	if err := mailTransport.Close(); err != nil {
+		slog.Error("Closing the mail transport failed", "error", err)
+	}
+	// Last, so spans of the work drained above are exported
+	if err := tracer.Shutdown(ctx); err != nil {
+		slog.Error("Flushing traces failed", "error", err)
+	}
+	slog.Info("Shutdown complete")
+}
+
+// newServerOptions returns the HTTP server settings of cfg
+func newServerOptions(cfg *config.Config) server.Options {
+	duration := func(value string) time.Duration {
+		d, _ := time.ParseDuration(value)
+		return d
+	}
+	return server.Options{
+		Addr:              ":" + cfg.Port,
+		ReadHeaderTimeout: duration(cfg.HTTPReadHeaderTimeout),
+		ReadTimeout:       duration(cfg.HTTPReadTimeout),
+		WriteTimeout:      duration(cfg.HTTPWriteTimeout),
+		IdleTimeout:       duration(cfg.HTTPIdleTimeout),
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+	}
+}
+
+// newRenderer loads and checks the email templates for cfg
+func newRenderer(cfg *config.Config) (*mail.Renderer, error) {
+	renderer, err := mail.NewRenderer(mail.RendererOptions{
+		OverrideDir:   cfg.EmailTemplateDir,
+		DefaultLocale: cfg.EmailDefaultLocale,
+		Branding: mail.Branding{
+			AppName:      cfg.AppName,
+			AppURL:       cfg.AppURL,
+			LogoURL:      cfg.AppLogoURL,
+			PrimaryColor: cfg.AppPrimaryColor,
+			SupportEmail: cfg.AppSupportEmail,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := renderer.Check(); err != nil {
+		return nil, err
+	}
+	return renderer, nil
+}
 
-	// 9. Start Server
-	log.Printf("Server running on port %s", cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+// newCatalog loads the message catalogs for cfg and warns about messages
+// that are not translated yet
+func newCatalog(cfg *config.Config) (*i18n.Catalog, error) {
+	catalog, err := i18n.Load(cfg.I18nDir, cfg.DefaultLanguage)
+	if err != nil {
+		return nil, err
+	}
+	for lang, keys := range catalog.Missing() {
+		slog.Warn("Messages without translation fall back to the default language", "language", lang, "keys", keys)
 	}
+	return catalog, nil
+}
+
+// fatal logs err and exits, for failures the API cannot start without
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
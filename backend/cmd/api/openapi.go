@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"auth-go/internal/domain"
+	"auth-go/internal/export"
+	"auth-go/internal/handler"
+	"auth-go/internal/health"
+	"auth-go/internal/mail"
+	"auth-go/internal/openapi"
+	"auth-go/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Security schemes of the API
+const (
+	bearerAuth  = "bearerAuth"
+	metricsAuth = "metricsToken"
+)
+
+// newOpenAPIDocument documents every route registered in main, including the
+// ones only mounted in some configurations. Request and response schemas
+// come from the handler types; keep the descriptions here in step with the
+// routes.
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Auth API",
+		Version:     "1.0.0",
+		Description: "Authentication and user management. Errors are RFC 7807 problem details; messages follow Accept-Language.",
+	}, problem.Details{})
+	doc.Components.SecuritySchemes[bearerAuth] = &openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Token returned by POST /api/auth/login"}
+	doc.Components.SecuritySchemes[metricsAuth] = &openapi.SecurityScheme{Type: "http", Scheme: "bearer", Description: "METRICS_TOKEN"}
+	// Names clearer than the Go ones, out of their package
+	doc.Define("HealthReport", health.Report{})
+	doc.Define("HealthCheckResult", health.Result{})
+	doc.Define("CapturedMail", mail.Message{})
+
+	// Probes
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/healthz", Tags: []string{"health"},
+		Summary:   "Liveness probe",
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.LiveResponse{}}},
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/readyz", Tags: []string{"health"},
+		Summary: "Readiness probe",
+		Responses: map[int]openapi.Body{
+			http.StatusOK:                 {Value: handler.ReadyResponse{}},
+			http.StatusServiceUnavailable: {Description: "A critical dependency is down, or the server is shutting down", Value: handler.ReadyResponse{}},
+		},
+	})
+
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/csp-report", Tags: []string{"security"},
+		Summary:     "Collect CSP violation reports",
+		Description: "Sent by browsers, in the report-uri or Reporting API format. Violations are logged.",
+		BodyTypes:   []string{"application/csp-report", "application/reports+json", openapi.JSONContentType},
+		Responses:   map[int]openapi.Body{http.StatusNoContent: {}},
+		Errors:      []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests},
+	})
+
+	// Auth
+	credentialErrors := []int{http.StatusBadRequest, http.StatusTooManyRequests}
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/auth/register", Tags: []string{"auth"},
+		Summary:     "Register an account",
+		Description: "The locale defaults to the negotiated Accept-Language. Disabled with the registration feature flag.",
+		Body:        domain.RegisterInput{},
+		Responses:   map[int]openapi.Body{http.StatusCreated: {Value: handler.DataResponse[*domain.User]{}}},
+		Errors:      append(credentialErrors, http.StatusForbidden, http.StatusConflict),
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/auth/login", Tags: []string{"auth"},
+		Summary:   "Log in",
+		Body:      domain.LoginInput{},
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.LoginResponse{}}},
+		Errors:    append(credentialErrors, http.StatusUnauthorized),
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/auth/forgot-password", Tags: []string{"auth"},
+		Summary:     "Request a password reset link",
+		Description: "Answers the same whether or not the email is registered.",
+		Body:        domain.ForgotPasswordInput{},
+		Responses:   map[int]openapi.Body{http.StatusOK: {Value: handler.MessageResponse{}}},
+		Errors:      credentialErrors,
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/auth/reset-password", Tags: []string{"auth"},
+		Summary:   "Reset a password with an emailed token",
+		Body:      domain.ResetPasswordInput{},
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.MessageResponse{}}},
+		Errors:    credentialErrors,
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/auth/me", Tags: []string{"auth"}, Security: bearerAuth,
+		Summary:   "Current user",
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.DataResponse[*domain.User]{}}},
+		Errors:    []int{http.StatusNotFound},
+	})
+
+	// Users
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/users", Tags: []string{"users"}, Security: bearerAuth,
+		Summary:     "List users",
+		Description: "Keyset paginated: pass meta.next or meta.prev back as cursor. The legacy page parameter continues with cursors from there.",
+		Params: append([]openapi.Param{
+			openapi.Query("sort", openapi.String(), "Field to sort by (created_at, name, email or relevance), prefixed with - for descending order"),
+			openapi.Query("limit", openapi.Integer(), "Page size, 10 by default"),
+			openapi.Query("page", openapi.Integer(), "Page number, for clients not using cursors"),
+			openapi.Query("cursor", openapi.String(), "Cursor from meta.next or meta.prev"),
+			openapi.Query("count", openapi.Enum(domain.CountExact, domain.CountApprox, domain.CountNone), "How meta.total is computed, exact by default"),
+		}, userFilterParams()...),
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.UserListResponse{}}},
+		Errors:    []int{http.StatusBadRequest, http.StatusTooManyRequests},
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/users/profile", Tags: []string{"users"}, Security: bearerAuth,
+		Summary:   "Current user",
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.DataResponse[*domain.User]{}}},
+		Errors:    []int{http.StatusNotFound},
+	})
+
+	// Admin
+	adminErrors := []int{http.StatusForbidden}
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/admin/config", Tags: []string{"admin"}, Security: bearerAuth,
+		Summary:     "Effective configuration",
+		Description: "Secrets are redacted.",
+		Responses:   map[int]openapi.Body{http.StatusOK: {Value: handler.ConfigResponse{}}},
+		Errors:      adminErrors,
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/admin/health", Tags: []string{"admin", "health"}, Security: bearerAuth,
+		Summary:   "Status of every dependency check",
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.HealthStatusResponse{}}},
+		Errors:    adminErrors,
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/admin/users/import", Tags: []string{"admin"}, Security: bearerAuth,
+		Summary:     "Import users from a CSV or JSONL file",
+		Description: "The file is the raw body or the file field of a multipart form. Rejected imports answer a problem with the report of the rows checked so far.",
+		Params: []openapi.Param{
+			openapi.Query("format", openapi.Enum(domain.ImportFormatCSV, domain.ImportFormatJSONL), "Detected from the file name or content type when omitted"),
+			openapi.Query("mode", openapi.Enum(domain.ImportModeInsert, domain.ImportModeSkip, domain.ImportModeUpsert), "What happens to rows whose email exists, insert by default"),
+			openapi.Query("dry_run", openapi.Boolean(), "Validate every row without writing"),
+			openapi.Query("invite", openapi.Boolean(), "Email created users a link to set their password"),
+		},
+		BodyTypes: []string{"text/csv", "application/jsonl", "application/x-ndjson", "multipart/form-data"},
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.DataResponse[*domain.UserImportReport]{}}},
+		Errors:    append(adminErrors, http.StatusBadRequest, http.StatusRequestEntityTooLarge),
+	})
+
+	exportParams := append([]openapi.Param{
+		openapi.Query("format", openapi.Enum(export.FormatCSV, export.FormatJSONL, export.FormatXLSX), "File format, csv by default"),
+		openapi.Query("columns", openapi.String(), "Comma separated subset of "+strings.Join(domain.UserExportColumns, ", ")),
+	}, userFilterParams()...)
+	exportTypes := []string{export.ContentType(export.FormatCSV), export.ContentType(export.FormatJSONL), export.ContentType(export.FormatXLSX)}
+	jobCreated := openapi.Body{Description: "Export job started", Value: handler.ExportJobResponse{}, Headers: map[string]string{"Location": "URL of the job"}}
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/admin/users/export", Tags: []string{"admin", "export"}, Security: bearerAuth,
+		Summary:     "Export users",
+		Description: "Streams the file, unless the export is over the configured row count or async is set, in which case it runs as a background job.",
+		Params:      append(exportParams, openapi.Query("async", openapi.Boolean(), "Always run as a background job")),
+		Responses: map[int]openapi.Body{
+			http.StatusOK:       {Description: "Export file", Types: exportTypes, Headers: map[string]string{"Content-Disposition": "Attachment file name"}},
+			http.StatusAccepted: jobCreated,
+		},
+		Errors: append(adminErrors, http.StatusBadRequest),
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodPost, Path: "/api/admin/users/export/jobs", Tags: []string{"admin", "export"}, Security: bearerAuth,
+		Summary:   "Start a background export",
+		Params:    exportParams,
+		Responses: map[int]openapi.Body{http.StatusAccepted: jobCreated},
+		Errors:    append(adminErrors, http.StatusBadRequest),
+	})
+	jobID := openapi.Path("id", openapi.String(), "Export job ID")
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/admin/users/export/jobs/:id", Tags: []string{"admin", "export"}, Security: bearerAuth,
+		Summary:   "Export job status",
+		Params:    []openapi.Param{jobID},
+		Responses: map[int]openapi.Body{http.StatusOK: {Value: handler.ExportJobResponse{}}},
+		Errors:    append(adminErrors, http.StatusNotFound),
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/api/admin/users/export/jobs/:id/download", Tags: []string{"admin", "export"}, Security: bearerAuth,
+		Summary:   "Download a finished export",
+		Params:    []openapi.Param{jobID},
+		Responses: map[int]openapi.Body{http.StatusOK: {Description: "Export file", Types: exportTypes}},
+		Errors:    append(adminErrors, http.StatusNotFound, http.StatusConflict),
+	})
+
+	// Debug mode only
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/_dev/mail", Tags: []string{"dev"},
+		Summary:     "Captured emails",
+		Description: "Mounted outside release mode unless DEV_MAIL_CAPTURE is off. HTML unless JSON is asked for.",
+		Responses:   map[int]openapi.Body{http.StatusOK: {Value: handler.DataResponse[[]*mail.Message]{}, Types: []string{"text/html"}}},
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/_dev/mail/:id", Tags: []string{"dev"},
+		Summary:   "A captured email",
+		Params:    []openapi.Param{openapi.Query("format", openapi.Enum("html", "text", "raw"), "Part to show, html by default")},
+		Responses: map[int]openapi.Body{http.StatusOK: {Types: []string{"text/html", "text/plain"}}},
+		Errors:    []int{http.StatusNotFound},
+	})
+
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/openapi.json", Tags: []string{"docs"},
+		Summary:   "This document",
+		Responses: map[int]openapi.Body{http.StatusOK: {Types: []string{openapi.JSONContentType}}},
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/docs", Tags: []string{"docs"},
+		Summary:     "Swagger UI",
+		Description: "Outside release mode only.",
+		Responses:   map[int]openapi.Body{http.StatusOK: {Types: []string{"text/html"}}},
+	})
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/docs/assets/*file", Tags: []string{"docs"},
+		Summary:   "Swagger UI assets",
+		Responses: map[int]openapi.Body{http.StatusOK: {}},
+		Errors:    []int{http.StatusNotFound},
+	})
+
+	doc.Add(openapi.Route{
+		Method: http.MethodGet, Path: "/metrics", Tags: []string{"metrics"}, Security: metricsAuth,
+		Summary:     "Prometheus metrics",
+		Description: "Mounted here when METRICS_TOKEN is set without METRICS_ADDR.",
+		Responses:   map[int]openapi.Body{http.StatusOK: {Types: []string{"text/plain"}}},
+	})
+	return doc
+}
+
+// userFilterParams are the filters shared by the user list and export
+func userFilterParams() []openapi.Param {
+	return []openapi.Param{
+		openapi.Query("search", openapi.String(), "Matches names and emails"),
+		openapi.Query("search_mode", openapi.Enum(domain.SearchAuto, domain.SearchPrefix, domain.SearchFullText, domain.SearchFuzzy), "auto by default"),
+		openapi.Query("status", openapi.Enum(domain.StatusActive, domain.StatusSuspended), ""),
+		openapi.Query("role", openapi.Enum(domain.RoleUser, domain.RoleAdmin), ""),
+		openapi.Query("verified", openapi.Boolean(), "Whether the email is verified"),
+		openapi.Query("created_from", openapi.String(), "Date (2006-01-02) or RFC 3339 timestamp, inclusive"),
+		openapi.Query("created_to", openapi.String(), "Date (2006-01-02, inclusive) or RFC 3339 timestamp (exclusive)"),
+	}
+}
+
+// checkDocumented fails for routes registered without being documented, so
+// the document cannot fall behind the router
+func checkDocumented(doc *openapi.Document, routes gin.RoutesInfo) error {
+	var missing []string
+	for _, route := range routes {
+		if !doc.Documented(route.Method, route.Path) {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("routes missing from the OpenAPI document in cmd/api/openapi.go: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
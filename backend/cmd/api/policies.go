@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"auth-go/internal/config"
+	"auth-go/internal/middleware"
+	"auth-go/pkg/utils"
+)
+
+// httpPolicies are the CORS and security header policies of the router,
+// rebuilt whenever the configuration is reloaded
+type httpPolicies struct {
+	cors     *middleware.Policies[middleware.CORSPolicy]
+	security *middleware.Policies[middleware.SecurityPolicy]
+}
+
+func newHTTPPolicies(cfg *config.Config) (*httpPolicies, error) {
+	p := &httpPolicies{
+		cors:     &middleware.Policies[middleware.CORSPolicy]{},
+		security: &middleware.Policies[middleware.SecurityPolicy]{},
+	}
+	apply, err := p.prepare(cfg)
+	if err != nil {
+		return nil, err
+	}
+	apply()
+	return p, nil
+}
+
+// prepare builds the policies of cfg and returns a function installing them
+func (p *httpPolicies) prepare(cfg *config.Config) (func(), error) {
+	origins, err := parseOrigins(cfg.AllowedOrigins())
+	if err != nil {
+		return nil, err
+	}
+	adminOrigins, err := parseOrigins(cfg.AdminAllowedOrigins())
+	if err != nil {
+		return nil, err
+	}
+
+	directives, err := middleware.ParseCSP(cfg.CSPPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("CSP_POLICY: %w", err)
+	}
+	trustedProxies, err := middleware.ParseNetworks(cfg.TrustedProxyList())
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	hstsMaxAge, _ := time.ParseDuration(cfg.HSTSMaxAge)
+
+	security := middleware.SecurityPolicy{
+		CSP: middleware.CSP{
+			Directives: directives,
+			ReportOnly: cfg.CSPReportOnly,
+			ReportURI:  cfg.CSPReportURI,
+		},
+		PermissionsPolicy:         cfg.PermissionsPolicy,
+		CrossOriginOpenerPolicy:   cfg.CrossOriginOpenerPolicy,
+		CrossOriginEmbedderPolicy: cfg.CrossOriginEmbedderPolicy,
+		HSTS: middleware.HSTS{
+			MaxAge:            hstsMaxAge,
+			IncludeSubdomains: cfg.HSTSIncludeSubdomains,
+			Preload:           cfg.HSTSPreload,
+		},
+		TrustedProxies: trustedProxies,
+	}
+
+	// Captured emails render as they would in a mail client: inline styles
+	// and remote images
+	devMail := security
+	devMail.CSP = security.CSP.
+		With("style-src", "'self'", "'unsafe-inline'").
+		With("img-src", "*", "data:")
+	devMail.CrossOriginEmbedderPolicy = ""
+
+	// Swagger UI starts from an inline script and draws data: images
+	docs := security
+	docs.CSP = security.CSP.With("img-src", "'self'", "data:")
+	docs.CSP.Nonce = true
+
+	return func() {
+		p.cors.Set(middleware.CORSPolicy{AllowedOrigins: origins}, map[string]middleware.CORSPolicy{
+			"/api/admin": {AllowedOrigins: adminOrigins},
+		})
+		p.security.Set(security, map[string]middleware.SecurityPolicy{
+			"/_dev/mail": devMail,
+			"/docs":      docs,
+		})
+	}, nil
+}
+
+func parseOrigins(values []string) ([]utils.OriginPattern, error) {
+	patterns := make([]utils.OriginPattern, 0, len(values))
+	for _, value := range values {
+		pattern, err := utils.ParseOriginPattern(value)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"auth-go/internal/config"
+	"auth-go/internal/domain"
+	"auth-go/internal/handler"
+	"auth-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiRoutes holds what the routes of the API are served by. Every route
+// registered here must be documented in openapi.go.
+type apiRoutes struct {
+	cfg         *config.Config
+	configStore *config.Store
+	userRepo    domain.UserRepository
+
+	auth       *handler.AuthHandler
+	users      *handler.UserHandler
+	userImport *handler.UserImportHandler
+	userExport *handler.UserExportHandler
+	config     *handler.ConfigHandler
+	cspReport  *handler.CSPReportHandler
+	health     *handler.HealthHandler
+	openAPI    *handler.OpenAPIHandler
+	// devMail is nil unless outgoing mail is captured
+	devMail *handler.DevMailHandler
+	// metrics is nil unless /metrics is served on the API port
+	metrics http.Handler
+}
+
+func (rt *apiRoutes) register(r *gin.Engine) {
+	cfg, configStore := rt.cfg, rt.configStore
+
+	r.NoRoute(handler.NotFound)
+	// Probes sit outside /api, away from its rate limit
+	r.GET("/healthz", rt.health.Live)
+	r.GET("/readyz", rt.health.Ready)
+
+	r.GET("/openapi.json", rt.openAPI.Document)
+	if cfg.GinMode != "release" {
+		r.GET("/docs", rt.openAPI.SwaggerUI)
+		r.GET("/docs/assets/*file", rt.openAPI.Assets)
+	}
+
+	requestTimeout, _ := time.ParseDuration(cfg.RequestTimeout)
+	api := r.Group("/api")
+	api.Use(middleware.TimeoutMiddleware(requestTimeout))
+	api.Use(middleware.RateLimitMiddleware(func() middleware.RateLimit {
+		current := configStore.Current()
+		return middleware.RateLimit{RPS: current.RateLimitRPS, Burst: current.RateLimitBurst}
+	}))
+	{
+		api.POST("/csp-report", rt.cspReport.Collect)
+
+		// Credential endpoints get a stricter limit against brute forcing
+		authLimit := middleware.RateLimitMiddleware(func() middleware.RateLimit {
+			current := configStore.Current()
+			return middleware.RateLimit{RPS: current.AuthRateLimitRPS, Burst: current.AuthRateLimitBurst}
+		})
+		auth := api.Group("/auth")
+		{
+			auth.POST("/register", authLimit, middleware.FeatureMiddleware(configStore, config.FeatureRegistration), rt.auth.Register)
+			auth.POST("/login", authLimit, rt.auth.Login)
+			auth.POST("/forgot-password", authLimit, rt.auth.ForgotPassword)
+			auth.POST("/reset-password", authLimit, rt.auth.ResetPassword)
+
+			// Protected Auth Route (e.g., Get Current User)
+			auth.GET("/me", middleware.AuthMiddleware(cfg), rt.users.GetProfile)
+		}
+
+		users := api.Group("/users")
+		users.Use(middleware.AuthMiddleware(cfg))
+		{
+			users.GET("", rt.users.GetAllUsers)
+			users.GET("/profile", rt.users.GetProfile)
+		}
+	}
+
+	// Admin bulk operations run outside /api's group to get a longer timeout.
+	// The server's deadlines are extended past it, leaving time to answer.
+	bulkTimeout, _ := time.ParseDuration(cfg.BulkRequestTimeout)
+	admin := r.Group("/api/admin")
+	admin.Use(middleware.ServerTimeoutMiddleware(bulkTimeout+30*time.Second), middleware.TimeoutMiddleware(bulkTimeout))
+	admin.Use(middleware.AuthMiddleware(cfg), middleware.AdminMiddleware(rt.userRepo))
+	{
+		admin.GET("/config", rt.config.Get)
+		admin.GET("/health", rt.health.Status)
+
+		admin.POST("/users/import", middleware.BodyLimitMiddleware(handler.MaxImportBytes), middleware.FeatureMiddleware(configStore, config.FeatureUserImport), rt.userImport.Import)
+
+		export := admin.Group("/users/export")
+		export.Use(middleware.FeatureMiddleware(configStore, config.FeatureUserExport))
+		{
+			export.GET("", rt.userExport.Export)
+			export.POST("/jobs", rt.userExport.CreateJob)
+			export.GET("/jobs/:id", rt.userExport.GetJob)
+			export.GET("/jobs/:id/download", rt.userExport.Download)
+		}
+	}
+
+	if rt.devMail != nil {
+		dev := r.Group("/_dev")
+		{
+			dev.GET("/mail", rt.devMail.List)
+			dev.GET("/mail/:id", rt.devMail.Show)
+		}
+	}
+
+	if rt.metrics != nil {
+		r.GET("/metrics", gin.WrapH(rt.metrics))
+	}
+}
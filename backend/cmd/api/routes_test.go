@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"auth-go/internal/config"
+	"auth-go/internal/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRoutesDocumented registers every route, including the optional ones,
+// and checks that the OpenAPI document describes each of them
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{GinMode: "debug", RequestTimeout: "15s", BulkRequestTimeout: "10m"}
+	doc := newOpenAPIDocument()
+	openAPIHandler, err := handler.NewOpenAPIHandler(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := &apiRoutes{
+		cfg:         cfg,
+		configStore: config.NewStore(cfg, config.Options{}),
+		auth:        handler.NewAuthHandler(nil),
+		users:       handler.NewUserHandler(nil),
+		userImport:  handler.NewUserImportHandler(nil),
+		userExport:  handler.NewUserExportHandler(nil, 0),
+		config:      handler.NewConfigHandler(nil),
+		cspReport:   handler.NewCSPReportHandler(),
+		health:      handler.NewHealthHandler(nil),
+		openAPI:     openAPIHandler,
+		devMail:     handler.NewDevMailHandler(nil),
+		metrics:     http.NotFoundHandler(),
+	}
+	r := gin.New()
+	routes.register(r)
+
+	if err := checkDocumented(doc, r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
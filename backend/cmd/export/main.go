@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"auth-go/internal/config"
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+	"auth-go/internal/repository"
+	"auth-go/internal/service"
+)
+
+const usage = `Usage: export [flags]
+
+Exports the users matching the same filters as GET /api/users.
+
+Flags:
+`
+
+func main() {
+	var params domain.UserExportParams
+	output := flag.String("o", "-", "output file, - for stdout")
+	flag.StringVar(&params.Format, "format", "", "csv, jsonl or xlsx (detected from -o by default, csv for stdout)")
+	flag.StringVar(&params.Columns, "columns", "", "comma separated columns: "+strings.Join(domain.UserExportColumns, ","))
+	flag.StringVar(&params.Filters.Search, "search", "", "search term")
+	flag.StringVar(&params.Filters.SearchMode, "search-mode", "", "auto, prefix, fulltext or fuzzy")
+	flag.StringVar(&params.Filters.Status, "status", "", "active or suspended")
+	flag.StringVar(&params.Filters.Role, "role", "", "user or admin")
+	flag.StringVar(&params.Filters.Verified, "verified", "", "true or false")
+	flag.StringVar(&params.Filters.CreatedFrom, "created-from", "", "date (2006-01-02) or RFC 3339 timestamp")
+	flag.StringVar(&params.Filters.CreatedTo, "created-to", "", "date (2006-01-02, inclusive) or RFC 3339 timestamp")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if params.Format == "" && *output != "-" {
+		params.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*output)), ".")
+	}
+
+	// 1. Load Config
+	cfg, err := config.Load(config.Options{SkipMail: true})
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 2. Connect Database
+	db := database.ConnectDB(cfg)
+
+	// 3. Run Export
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if *output != "-" {
+		if file, err = os.Create(*output); err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		out = file
+	}
+	buffered := bufio.NewWriterSize(out, 1<<20)
+
+	exportService := service.NewUserExportService(repository.NewUserRepository(db), repository.NewExportJobRepository(db), cfg)
+	rows, err := exportService.Export(ctx, buffered, params)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if file != nil {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*output)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Export failed after %d rows: %v", rows, err)
+	}
+	log.Printf("Exported %d users", rows)
+}
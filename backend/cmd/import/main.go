@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"auth-go/internal/config"
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+	"auth-go/internal/repository"
+	"auth-go/internal/service"
+)
+
+const usage = `Usage: import [flags] <file.csv|file.jsonl|->
+
+Imports users from a CSV file with a header row or a JSON Lines file.
+Columns: name, email, password, password_hash, locale, role.
+Invitation emails are queued in the outbox and sent by the API server.
+
+Flags:
+`
+
+func main() {
+	format := flag.String("format", "", "csv or jsonl (detected from the file extension by default)")
+	mode := flag.String("mode", domain.ImportModeInsert, "what to do with existing emails: insert (report an error), skip or upsert")
+	dryRun := flag.Bool("dry-run", false, "validate and report without writing anything")
+	invite := flag.Bool("invite", false, "email created users a link to set their password")
+	reportPath := flag.String("report", "", "write the full JSON report to this file")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer file.Close()
+		input = file
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			*format = domain.ImportFormatCSV
+		case ".jsonl", ".ndjson":
+			*format = domain.ImportFormatJSONL
+		default:
+			log.Fatal("Cannot detect the file format, pass -format")
+		}
+	}
+
+	// 1. Load Config
+	cfg, err := config.Load(config.Options{SkipMail: true})
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 2. Connect Database
+	db := database.ConnectDB(cfg)
+
+	// 3. Run Import, stopping between batches on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	importService := service.NewUserImportService(repository.NewUserRepository(db), repository.NewTxManager(db), cfg)
+	report, importErr := importService.Import(ctx, input, domain.UserImportOptions{
+		Format: *format,
+		Mode:   *mode,
+		DryRun: *dryRun,
+		Invite: *invite,
+	})
+
+	if report != nil {
+		printReport(report)
+		if *reportPath != "" {
+			if err := writeReport(*reportPath, report); err != nil {
+				log.Printf("Failed to write report: %v", err)
+			}
+		}
+	}
+	if importErr != nil {
+		log.Fatalf("Import failed: %v", importErr)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func printReport(report *domain.UserImportReport) {
+	prefix := ""
+	if report.DryRun {
+		prefix = "[dry run] "
+	}
+	fmt.Printf("%s%d rows: %d created, %d updated, %d skipped, %d failed, %d invited\n",
+		prefix, report.Total, report.Created, report.Updated, report.Skipped, report.Failed, report.Invited)
+
+	for _, row := range report.Errors {
+		for _, fieldErr := range row.Errors {
+			field := fieldErr.Field
+			if field == "" {
+				field = "row"
+			}
+			fmt.Printf("  row %d %s: %s %s\n", row.Row, row.Email, field, fieldErr.Message)
+		}
+	}
+}
+
+func writeReport(path string, report *domain.UserImportReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
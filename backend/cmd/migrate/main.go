@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"auth-go/internal/config"
+	"auth-go/internal/database"
+)
+
+const usage = `Usage: migrate <command> [flags]
+
+Commands:
+  up [-steps N]      apply pending migrations (all by default)
+  down [-steps N]    revert the last N applied migrations (1 by default)
+  status             list migrations and whether they are applied
+  create <name>      create a new empty up/down migration pair per driver
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	steps := flags.Int("steps", 0, "number of migrations to apply or revert")
+	dir := flags.String("dir", database.MigrationsDir, "directory for new migrations (create only)")
+	flags.Parse(os.Args[2:])
+
+	// create does not need a database connection
+	if command == "create" {
+		if flags.NArg() < 1 {
+			log.Fatal("create requires a migration name")
+		}
+		created, err := database.CreateMigration(*dir, flags.Arg(0))
+		for _, path := range created {
+			fmt.Printf("Created %s\n", path)
+		}
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		return
+	}
+
+	// 1. Load Config
+	cfg, err := config.Load(config.Options{SkipMail: true})
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 2. Connect Database without applying migrations implicitly
+	cfg.DBAutoMigrate = false
+	db := database.ConnectDB(cfg)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	// 3. Run Command
+	switch command {
+	case "up":
+		applied, err := migrator.Up(*steps)
+		for _, m := range applied {
+			fmt.Printf("Applied  %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(*steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.ChecksumMismatch {
+				state += " (modified after apply!)"
+			}
+			fmt.Printf("%d_%-40s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
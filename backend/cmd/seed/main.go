@@ -1,98 +1,165 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 
 	"auth-go/internal/config"
 	"auth-go/internal/database"
-	"auth-go/internal/domain"
+	"auth-go/internal/seed"
 	"auth-go/pkg/utils"
 
-	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
+const usage = `Usage: seed [flags]
+
+Generates realistic users, or loads fixture files with -fixtures.
+The same -seed and options always generate the same users, and an
+interrupted run continues from its -checkpoint when started again.
+
+Examples:
+  seed -count 3000000 -concurrency 8
+  seed -count 50000 -locales en=3,id=2,de=1 -orgs Acme=5,Globex=2,Initech=1 -suspended 0.05
+  seed -fixtures cmd/seed/fixtures/demo.yaml
+
+Flags:
+`
+
 func main() {
+	count := flag.Int("count", 10000, "number of users to generate")
+	batchSize := flag.Int("batch", 1000, "users per INSERT, at most about 2900 on SQLite and 5900 on MySQL and PostgreSQL")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "parallel insert workers")
+	seedValue := flag.Uint64("seed", 1, "random seed, the same seed generates the same users")
+	locales := flag.String("locales", "en=1,id=1", "weighted locales of names: "+strings.Join(seed.Locales(), ", "))
+	roles := flag.String("roles", "user=99,admin=1", "weighted roles")
+	orgs := flag.String("orgs", "", "weighted organizations, e.g. Acme=3,Globex=1 (none by default)")
+	verified := flag.Float64("verified", 0.8, "fraction of users with a verified email")
+	suspended := flag.Float64("suspended", 0.02, "fraction of suspended users")
+	createdWithin := flag.Duration("created-within", 365*24*time.Hour, "spread creation dates over this period")
+	createdUntil := flag.String("created-until", "", "latest creation date (2006-01-02), today by default or the date of the run being resumed")
+	password := flag.String("password", "password", "password of every generated user")
+	checkpoint := flag.String("checkpoint", "seed.checkpoint.json", "progress file for resuming, empty to disable")
+	fresh := flag.Bool("fresh", false, "ignore the checkpoint and start over")
+	fixtures := flag.String("fixtures", "", "comma separated YAML/JSON fixture files to load instead of generating")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Fixtures replace generation unless -count is given explicitly
+	generate := *fixtures == ""
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "count" {
+			generate = true
+		}
+	})
+
+	// Check everything before connecting
+	var loaded []*seed.Fixture
+	if *fixtures != "" {
+		for _, path := range strings.Split(*fixtures, ",") {
+			fixture, err := seed.ReadFixture(strings.TrimSpace(path))
+			if err != nil {
+				log.Fatalf("Invalid fixture: %v", err)
+			}
+			loaded = append(loaded, fixture)
+		}
+	}
+	genOpts, err := generatorOptions(*seedValue, *locales, *roles, *orgs, *verified, *suspended, *createdUntil, *createdWithin)
+	if generate && err != nil {
+		log.Fatalf("Invalid options: %v", err)
+	}
+
 	// 1. Load Config
-	cfg, err := config.LoadConfig()
+	cfg, err := config.Load(config.Options{SkipMail: true})
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
 	// 2. Connect Database
 	db := database.ConnectDB(cfg)
+	// Failed and slow bulk inserts would be logged with all their values;
+	// errors are reported below instead
+	db.Logger = db.Logger.LogMode(logger.Silent)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 3. Load Fixtures
+	if len(loaded) > 0 {
+		written, err := seed.LoadFixtures(ctx, db, loaded, cfg.EmailDefaultLocale)
+		if err != nil {
+			log.Fatalf("Failed to load fixtures: %v", err)
+		}
+		log.Printf("Loaded %d fixture users", written)
+	}
+	if !generate {
+		return
+	}
 
-	// 3. Seed Data
-	log.Println("Seeding database...")
-	seedUsers(db)
-	log.Println("Database seeded successfully!")
-}
-
-func seedUsers(db *gorm.DB) {
-	// 1. Hash password once (very important for performance)
-	password, err := utils.HashPassword("password")
+	// 4. Generate Users, hashing the shared password once
+	hash, err := utils.HashPassword(*password)
 	if err != nil {
 		log.Fatalf("Failed to hash password: %v", err)
 	}
 
-	totalUsers := 3000000
-	batchSize := 2000 // Insert 2000 users per query
-
-	// Create "Admin User" specifically first if not exists
-	var admin domain.User
-	if err := db.Where("email = ?", "admin@example.com").First(&admin).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			db.Create(&domain.User{
-				Name:      "Admin User",
-				Email:     "admin@example.com",
-				Password:  password,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			})
-			log.Println("Created Admin User")
+	log.Printf("Seeding %d users with %d workers...", *count, *concurrency)
+	result, err := seed.Run(ctx, db, seed.NewGenerator(genOpts, hash), seed.RunOptions{
+		Count:       *count,
+		BatchSize:   *batchSize,
+		Concurrency: *concurrency,
+		Checkpoint:  *checkpoint,
+		Fresh:       *fresh,
+	})
+	if result != nil && result.Resumed > 0 {
+		log.Printf("Resumed from user %d", result.Resumed)
+	}
+	if err != nil {
+		if *checkpoint != "" {
+			log.Printf("Progress saved to %s, run the same command again to continue", *checkpoint)
 		}
+		log.Fatalf("Seeding failed: %v", err)
 	}
+	log.Printf("Finished! Inserted %d new users in %v", result.Inserted, result.Elapsed.Round(time.Millisecond))
+}
 
-	start := time.Now()
-	users := make([]domain.User, 0, batchSize)
-
-	// Start from 1 up to totalUsers
-	count := 0
-
-	// Transaction optimization can also help, but GORM batch insert wraps it usually.
-	// We'll manually chunk the loop to manage memory.
-
-	log.Printf("Starting bulk insert of %d users...", totalUsers)
-
-	for i := 1; i <= totalUsers; i++ {
-		users = append(users, domain.User{
-			Name:      fmt.Sprintf("User %d", i),
-			Email:     fmt.Sprintf("user%d@example.com", i),
-			Password:  password, // Reuse hashed password
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		})
-
-		// If batch is full, insert and reset
-		if len(users) >= batchSize {
-			if err := db.CreateInBatches(users, batchSize).Error; err != nil {
-				log.Printf("Error inserting batch at index %d: %v", i, err)
-			}
-			users = users[:0] // Reset slice but keep capacity
-			count += batchSize
-
-			if count%100000 == 0 {
-				log.Printf("Inserted %d users... (Elapsed: %v)", count, time.Since(start))
-			}
+func generatorOptions(seedValue uint64, locales, roles, orgs string, verified, suspended float64, createdUntil string, createdWithin time.Duration) (seed.GeneratorOptions, error) {
+	opts := seed.GeneratorOptions{
+		Seed:          seedValue,
+		Verified:      verified,
+		Suspended:     suspended,
+		CreatedWithin: createdWithin,
+	}
+	if createdUntil != "" {
+		until, err := time.Parse(time.DateOnly, createdUntil)
+		if err != nil {
+			return opts, fmt.Errorf("created-until must be a date (2006-01-02)")
 		}
+		opts.CreatedUntil = until
 	}
 
-	// Insert potential remaining users
-	if len(users) > 0 {
-		db.CreateInBatches(users, len(users))
-		count += len(users)
+	var err error
+	if opts.Locales, err = seed.ParseWeights(locales); err != nil {
+		return opts, err
 	}
-
-	log.Printf("Finished! Total inserted: %d users in %v", count, time.Since(start))
+	if len(opts.Locales.Values) == 0 {
+		return opts, fmt.Errorf("at least one locale is required")
+	}
+	if opts.Roles, err = seed.ParseWeights(roles); err != nil {
+		return opts, err
+	}
+	if opts.Orgs, err = seed.ParseWeights(orgs); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
 }
@@ -1,45 +1,426 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// Config is the application configuration. Settings tagged reload:"true"
+// can change while the API runs, see Store.
 type Config struct {
+	// DBDriver is "mysql", "postgres" or "sqlite"
+	DBDriver   string `mapstructure:"DB_DRIVER"`
 	DBUser     string `mapstructure:"DB_USER"`
 	DBPassword string `mapstructure:"DB_PASSWORD"`
 	DBHost     string `mapstructure:"DB_HOST"`
 	DBPort     string `mapstructure:"DB_PORT"`
 	DBName     string `mapstructure:"DB_NAME"`
+	// DBSSLMode is the PostgreSQL sslmode
+	DBSSLMode string `mapstructure:"DB_SSLMODE"`
+	// DBPath is the SQLite database file, or ":memory:"
+	DBPath string `mapstructure:"DB_PATH"`
+	// DBAutoMigrate applies pending migrations on startup; disable it when
+	// migrations are run separately with cmd/migrate
+	DBAutoMigrate bool `mapstructure:"DB_AUTO_MIGRATE"`
 
 	JWTSecret    string `mapstructure:"JWT_SECRET"`
 	JWTExpiredIn string `mapstructure:"JWT_EXPIRED_IN"`
+	// CursorSecret signs pagination cursors, defaults to JWTSecret
+	CursorSecret string `mapstructure:"CURSOR_SECRET"`
 
 	SMTPHost     string `mapstructure:"SMTP_HOST"`
 	SMTPPort     int    `mapstructure:"SMTP_PORT"`
 	SMTPEmail    string `mapstructure:"SMTP_EMAIL"`
 	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+	// SMTPTLSMode is "starttls" or "tls" (implicit TLS, usually port 465)
+	SMTPTLSMode     string `mapstructure:"SMTP_TLS_MODE"`
+	SMTPPoolSize    int    `mapstructure:"SMTP_POOL_SIZE"`
+	SMTPIdleTimeout string `mapstructure:"SMTP_IDLE_TIMEOUT"`
+
+	// MailTransport is "smtp", "file" (writes .eml files to MailFileDir) or "memory"
+	MailTransport string `mapstructure:"MAIL_TRANSPORT"`
+	MailFrom      string `mapstructure:"MAIL_FROM"`
+	MailFileDir   string `mapstructure:"MAIL_FILE_DIR"`
+
+	// DevMailCapture controls the /_dev/mail catcher outside release mode:
+	// "off", "on" (capture delivered mail) or "only" (capture without
+	// delivering, the default, so development needs no SMTP server)
+	DevMailCapture string `mapstructure:"DEV_MAIL_CAPTURE"`
+
+	AppName         string `mapstructure:"APP_NAME" reload:"true"`
+	AppURL          string `mapstructure:"APP_URL"`
+	AppLogoURL      string `mapstructure:"APP_LOGO_URL" reload:"true"`
+	AppPrimaryColor string `mapstructure:"APP_PRIMARY_COLOR" reload:"true"`
+	AppSupportEmail string `mapstructure:"APP_SUPPORT_EMAIL" reload:"true"`
+
+	EmailTemplateDir   string `mapstructure:"EMAIL_TEMPLATE_DIR" reload:"true"`
+	EmailDefaultLocale string `mapstructure:"EMAIL_DEFAULT_LOCALE"`
+
+	// I18nDir holds extra message catalogs (<language>.json) for API
+	// responses; DefaultLanguage answers clients whose Accept-Language has
+	// no catalog
+	I18nDir         string `mapstructure:"I18N_DIR" reload:"true"`
+	DefaultLanguage string `mapstructure:"DEFAULT_LANGUAGE"`
+
+	OutboxWorkers      int    `mapstructure:"OUTBOX_WORKERS"`
+	OutboxBatchSize    int    `mapstructure:"OUTBOX_BATCH_SIZE"`
+	OutboxMaxAttempts  int    `mapstructure:"OUTBOX_MAX_ATTEMPTS"`
+	OutboxPollInterval string `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	// OutboxRetention is how long sent messages are kept, e.g. "168h"
+	OutboxRetention string `mapstructure:"OUTBOX_RETENTION"`
+
+	// ExportDir holds the files of background exports. Use a shared volume
+	// when running several instances.
+	ExportDir string `mapstructure:"EXPORT_DIR"`
+	// ExportTTL is how long a finished export can be downloaded, e.g. "24h"
+	ExportTTL string `mapstructure:"EXPORT_TTL"`
+	// ExportSyncMaxRows is the largest export streamed directly in the
+	// response; bigger ones run as background jobs
+	ExportSyncMaxRows int64 `mapstructure:"EXPORT_SYNC_MAX_ROWS"`
+	ExportMaxJobs     int   `mapstructure:"EXPORT_MAX_JOBS"`
+
+	// RequestTimeout bounds how long a single API request may run, e.g. "15s"
+	RequestTimeout string `mapstructure:"REQUEST_TIMEOUT"`
+	// BulkRequestTimeout replaces RequestTimeout for admin bulk operations
+	// such as imports, e.g. "10m"
+	BulkRequestTimeout string `mapstructure:"BULK_REQUEST_TIMEOUT"`
 
 	Port    string `mapstructure:"PORT"`
 	GinMode string `mapstructure:"GIN_MODE"`
+
+	// HTTPReadHeaderTimeout, HTTPReadTimeout, HTTPWriteTimeout and
+	// HTTPIdleTimeout configure the server, e.g. "30s". Admin bulk
+	// operations extend the read and write timeouts to BulkRequestTimeout.
+	HTTPReadHeaderTimeout string `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPReadTimeout       string `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout      string `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout       string `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	HTTPMaxHeaderBytes    int    `mapstructure:"HTTP_MAX_HEADER_BYTES"`
+	// MaxBodyBytes bounds request bodies, except user imports which have
+	// their own limit; 0 disables the limit
+	MaxBodyBytes int64 `mapstructure:"MAX_BODY_BYTES"`
+	// TLSCertFile and TLSKeyFile serve HTTPS directly. The files are
+	// reloaded when they change.
+	TLSCertFile string `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile  string `mapstructure:"TLS_KEY_FILE"`
+	// ShutdownTimeout bounds draining requests and background work on
+	// shutdown, e.g. "30s"
+	ShutdownTimeout string `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// CORSAllowedOrigins is a comma separated list of origins allowed to
+	// call the API with credentials. Patterns such as https://*.example.com
+	// match any subdomain.
+	CORSAllowedOrigins string `mapstructure:"CORS_ALLOWED_ORIGINS" reload:"true"`
+	// CORSAdminAllowedOrigins replaces CORSAllowedOrigins for /api/admin,
+	// e.g. to keep the admin API to an internal dashboard
+	CORSAdminAllowedOrigins string `mapstructure:"CORS_ADMIN_ALLOWED_ORIGINS" reload:"true"`
+
+	// CSPPolicy is the Content-Security-Policy of API responses, as
+	// semicolon separated directives
+	CSPPolicy string `mapstructure:"CSP_POLICY" reload:"true"`
+	// CSPReportOnly sends the policy as Content-Security-Policy-Report-Only,
+	// reporting violations without blocking anything
+	CSPReportOnly bool `mapstructure:"CSP_REPORT_ONLY" reload:"true"`
+	// CSPReportURI receives violation reports, empty to disable reporting
+	CSPReportURI      string `mapstructure:"CSP_REPORT_URI" reload:"true"`
+	PermissionsPolicy string `mapstructure:"PERMISSIONS_POLICY" reload:"true"`
+	// CrossOriginOpenerPolicy and CrossOriginEmbedderPolicy are left out of
+	// responses when empty
+	CrossOriginOpenerPolicy   string `mapstructure:"CROSS_ORIGIN_OPENER_POLICY" reload:"true"`
+	CrossOriginEmbedderPolicy string `mapstructure:"CROSS_ORIGIN_EMBEDDER_POLICY" reload:"true"`
+	// HSTSMaxAge is sent in Strict-Transport-Security on TLS requests only,
+	// e.g. "8760h"; empty disables HSTS
+	HSTSMaxAge            string `mapstructure:"HSTS_MAX_AGE" reload:"true"`
+	HSTSIncludeSubdomains bool   `mapstructure:"HSTS_INCLUDE_SUBDOMAINS" reload:"true"`
+	HSTSPreload           bool   `mapstructure:"HSTS_PRELOAD" reload:"true"`
+	// TrustedProxies lists the IPs or CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Forwarded-Proto headers are believed
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+	// RateLimitRPS is the sustained number of API requests per second allowed
+	// per client IP, with bursts of up to RateLimitBurst; 0 disables the limit
+	RateLimitRPS   float64 `mapstructure:"RATE_LIMIT_RPS" reload:"true"`
+	RateLimitBurst int     `mapstructure:"RATE_LIMIT_BURST" reload:"true"`
+	// AuthRateLimitRPS is a stricter limit for login, registration and
+	// password resets, e.g. 0.5 for one request every two seconds
+	AuthRateLimitRPS   float64 `mapstructure:"AUTH_RATE_LIMIT_RPS" reload:"true"`
+	AuthRateLimitBurst int     `mapstructure:"AUTH_RATE_LIMIT_BURST" reload:"true"`
+	// FeatureFlags turns optional features on or off, e.g.
+	// "registration=false,user_export=true", see Features
+	FeatureFlags string `mapstructure:"FEATURE_FLAGS" reload:"true"`
+	// LogLevel is "debug", "info", "warn" or "error"
+	LogLevel string `mapstructure:"LOG_LEVEL" reload:"true"`
+	// LogLevels overrides LogLevel per component, e.g.
+	// "database=debug,http=warn"; debug on database logs every statement
+	LogLevels string `mapstructure:"LOG_LEVELS" reload:"true"`
+	// LogFormat is "json" or "text"
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+
+	// MetricsAddr serves /metrics on a separate listener, e.g.
+	// "127.0.0.1:9090", out of reach of API clients
+	MetricsAddr string `mapstructure:"METRICS_ADDR"`
+	// MetricsToken, when set, must be sent as a bearer token to read
+	// /metrics. Without MetricsAddr it is required to serve /metrics on the
+	// API port.
+	MetricsToken string `mapstructure:"METRICS_TOKEN" reload:"true"`
+
+	// TracingExporter is "none", "otlp", "stdout" or "memory"
+	TracingExporter string `mapstructure:"TRACING_EXPORTER"`
+	// TracingOTLPEndpoint is the collector's host:port; when empty the
+	// standard OTEL_EXPORTER_OTLP_* variables apply
+	TracingOTLPEndpoint string `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	// TracingOTLPProtocol is "http/protobuf" or "grpc"
+	TracingOTLPProtocol string `mapstructure:"TRACING_OTLP_PROTOCOL"`
+	TracingOTLPInsecure bool   `mapstructure:"TRACING_OTLP_INSECURE"`
+	// TracingSampleRatio is the share of new traces recorded, from 0 to 1
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+	TracingServiceName string  `mapstructure:"TRACING_SERVICE_NAME"`
+
+	// HealthSMTPCheck is "off", "report" (shown in the admin status) or
+	// "require" (also fails readiness)
+	HealthSMTPCheck string `mapstructure:"HEALTH_SMTP_CHECK"`
+	// ShutdownDelay keeps serving requests this long after readiness starts
+	// failing on shutdown, so load balancers stop routing to the instance
+	// first, e.g. "5s"
+	ShutdownDelay string `mapstructure:"SHUTDOWN_DELAY"`
+}
+
+// AllowedOrigins returns CORSAllowedOrigins as a list
+func (c *Config) AllowedOrigins() []string {
+	return splitList(c.CORSAllowedOrigins)
 }
 
-func LoadConfig() (config *Config, err error) {
-	viper.SetConfigFile(".env")
-	viper.AutomaticEnv()
+// AdminAllowedOrigins returns the origins allowed for /api/admin
+func (c *Config) AdminAllowedOrigins() []string {
+	if c.CORSAdminAllowedOrigins == "" {
+		return c.AllowedOrigins()
+	}
+	return splitList(c.CORSAdminAllowedOrigins)
+}
 
-	err = viper.ReadInConfig()
-	if err != nil {
-		log.Fatal("Could not load config file:", err)
-		return
+// TrustedProxyList returns TrustedProxies as a list
+func (c *Config) TrustedProxyList() []string {
+	return splitList(c.TrustedProxies)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Options select where configuration is read from besides the environment
+type Options struct {
+	// File is a .env, .yaml, .yml, .toml or .json config file. When empty,
+	// CONFIG_FILE is used, then ./.env if it exists.
+	File string
+	// Args are command line flags such as --port=9000 or --config=app.yaml,
+	// one per setting. Nil skips flag parsing.
+	Args []string
+	// SkipMail leaves the mail settings unchecked, for commands such as
+	// cmd/migrate that never send mail
+	SkipMail bool
+}
+
+// LoadConfig reads the configuration without command line flags
+func LoadConfig() (*Config, error) {
+	return Load(Options{})
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// defaults, the config file, environment variables (or a file named by
+// <KEY>_FILE, for Docker and Kubernetes secrets) and flags, then validates
+// it. Flag parsing returns pflag.ErrHelp when --help is given.
+func Load(opts Options) (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	var flags *pflag.FlagSet
+	if opts.Args != nil {
+		flags = NewFlagSet()
+		if err := flags.Parse(opts.Args); err != nil {
+			return nil, err
+		}
+		if file, _ := flags.GetString("config"); file != "" {
+			opts.File = file
+		}
+	}
+
+	if err := readConfigFile(v, opts.File); err != nil {
+		return nil, err
+	}
+
+	for _, key := range Keys() {
+		if err := v.BindEnv(key); err != nil {
+			return nil, err
+		}
+		if err := readSecretFile(v, key); err != nil {
+			return nil, err
+		}
+		if flags != nil {
+			if flag := flags.Lookup(flagName(key)); flag != nil && flag.Changed {
+				v.Set(key, flag.Value.String())
+			}
+		}
+	}
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := config.validate(opts.SkipMail); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("DB_DRIVER", "mysql")
+	v.SetDefault("DB_SSLMODE", "disable")
+	v.SetDefault("DB_PATH", "auth-go.db")
+	v.SetDefault("DB_AUTO_MIGRATE", true)
+	v.SetDefault("JWT_EXPIRED_IN", "24h")
+	v.SetDefault("SMTP_TLS_MODE", "starttls")
+	v.SetDefault("MAIL_TRANSPORT", "smtp")
+	v.SetDefault("MAIL_FILE_DIR", "mail")
+	v.SetDefault("DEV_MAIL_CAPTURE", "only")
+	v.SetDefault("OUTBOX_RETENTION", "168h")
+	v.SetDefault("REQUEST_TIMEOUT", "15s")
+	v.SetDefault("BULK_REQUEST_TIMEOUT", "10m")
+	v.SetDefault("EXPORT_DIR", "exports")
+	v.SetDefault("EXPORT_TTL", "24h")
+	v.SetDefault("EXPORT_SYNC_MAX_ROWS", 100000)
+	v.SetDefault("APP_NAME", "Auth Go")
+	v.SetDefault("APP_URL", "http://localhost:5173")
+	v.SetDefault("EMAIL_DEFAULT_LOCALE", "en")
+	v.SetDefault("DEFAULT_LANGUAGE", "en")
+	v.SetDefault("PORT", "8080")
+	v.SetDefault("GIN_MODE", "debug")
+	v.SetDefault("HTTP_READ_HEADER_TIMEOUT", "5s")
+	v.SetDefault("HTTP_READ_TIMEOUT", "30s")
+	v.SetDefault("HTTP_WRITE_TIMEOUT", "30s")
+	v.SetDefault("HTTP_IDLE_TIMEOUT", "120s")
+	v.SetDefault("HTTP_MAX_HEADER_BYTES", 64<<10)
+	v.SetDefault("MAX_BODY_BYTES", 1<<20)
+	v.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	v.SetDefault("CORS_ALLOWED_ORIGINS", "http://localhost:5173,http://127.0.0.1:5173")
+	v.SetDefault("RATE_LIMIT_RPS", 20)
+	v.SetDefault("RATE_LIMIT_BURST", 40)
+	v.SetDefault("AUTH_RATE_LIMIT_RPS", 0.5)
+	v.SetDefault("AUTH_RATE_LIMIT_BURST", 10)
+	v.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("LOG_FORMAT", "json")
+	v.SetDefault("CSP_POLICY", "default-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'")
+	v.SetDefault("CSP_REPORT_URI", "/api/csp-report")
+	v.SetDefault("PERMISSIONS_POLICY", "camera=(), microphone=(), geolocation=(), payment=(), usb=()")
+	v.SetDefault("CROSS_ORIGIN_OPENER_POLICY", "same-origin")
+	v.SetDefault("CROSS_ORIGIN_EMBEDDER_POLICY", "require-corp")
+	v.SetDefault("HSTS_MAX_AGE", "8760h")
+	v.SetDefault("HSTS_INCLUDE_SUBDOMAINS", true)
+	v.SetDefault("TRACING_EXPORTER", "none")
+	v.SetDefault("TRACING_OTLP_PROTOCOL", "http/protobuf")
+	v.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	v.SetDefault("TRACING_SERVICE_NAME", "auth-go")
+	v.SetDefault("HEALTH_SMTP_CHECK", "off")
+}
+
+// readConfigFile loads path, or CONFIG_FILE, or ./.env when it exists. Only
+// an explicitly named file has to exist.
+func readConfigFile(v *viper.Viper, path string) error {
+	path, ok := configFilePath(path)
+	if !ok {
+		return nil
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".env":
+		v.SetConfigType("env")
+	case ".yaml", ".yml", ".toml", ".json":
+		v.SetConfigType(strings.TrimPrefix(ext, "."))
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .env, .yaml, .toml or .json", path)
+	}
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("could not read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configFilePath resolves the config file to read, reporting false when
+// there is none
+func configFilePath(path string) (string, bool) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
 	}
+	if path == "" {
+		if _, err := os.Stat(".env"); err != nil {
+			return "", false
+		}
+		path = ".env"
+	}
+	return path, true
+}
 
-	err = viper.Unmarshal(&config)
+// readSecretFile uses the content of the file named by <key>_FILE when key
+// itself is not set in the environment
+func readSecretFile(v *viper.Viper, key string) error {
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return nil
+	}
+	if _, ok := os.LookupEnv(key); ok {
+		return fmt.Errorf("both %s and %s_FILE are set, use only one", key, key)
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatal("Could not unmarshal config:", err)
-		return
+		return fmt.Errorf("could not read %s_FILE: %w", key, err)
 	}
+	// Secret files usually end with a newline that is not part of the value
+	v.Set(key, strings.TrimRight(string(data), "\r\n"))
+	return nil
+}
+
+// Keys returns the name of every setting, as used in the environment
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if key := t.Field(i).Tag.Get("mapstructure"); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// NewFlagSet returns the flags accepted by Load: --config and one flag per
+// setting, named like the setting in kebab case (JWT_EXPIRED_IN becomes
+// --jwt-expired-in)
+func NewFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet(filepath.Base(os.Args[0]), pflag.ContinueOnError)
+	flags.String("config", "", "config file (.env, .yaml, .toml or .json), defaults to CONFIG_FILE or ./.env")
+	for _, key := range Keys() {
+		flags.String(flagName(key), "", "overrides "+key)
+	}
+	flags.SortFlags = false
+	return flags
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
 
-	return
+// IsHelp reports whether err is the result of asking for --help
+func IsHelp(err error) bool {
+	return errors.Is(err, pflag.ErrHelp)
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Optional features that FEATURE_FLAGS can turn off or on
+const (
+	FeatureRegistration = "registration"
+	FeatureUserImport   = "user_import"
+	FeatureUserExport   = "user_export"
+	// FeatureOpenAPIValidation checks requests against the OpenAPI document
+	FeatureOpenAPIValidation = "openapi_validation"
+)
+
+// featureDefaults lists every known feature with its default state
+var featureDefaults = map[string]bool{
+	FeatureRegistration:      true,
+	FeatureUserImport:        true,
+	FeatureUserExport:        true,
+	FeatureOpenAPIValidation: false,
+}
+
+// Features returns the state of every known feature. FeatureFlags holds
+// comma separated name=bool pairs; a bare name turns the feature on.
+func (c *Config) Features() (map[string]bool, error) {
+	features := make(map[string]bool, len(featureDefaults))
+	for name, enabled := range featureDefaults {
+		features[name] = enabled
+	}
+	for _, item := range splitList(c.FeatureFlags) {
+		name, value, hasValue := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if _, ok := featureDefaults[name]; !ok {
+			return nil, fmt.Errorf("unknown feature %q, expected any of %s", name, strings.Join(featureNames(), ", "))
+		}
+		enabled := true
+		if hasValue {
+			var err error
+			if enabled, err = strconv.ParseBool(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("feature %s must be true or false, got %q", name, value)
+			}
+		}
+		features[name] = enabled
+	}
+	return features, nil
+}
+
+// Feature reports whether the named feature is on. FeatureFlags is
+// validated on load, so errors cannot happen here.
+func (c *Config) Feature(name string) bool {
+	features, err := c.Features()
+	if err != nil {
+		return featureDefaults[name]
+	}
+	return features[name]
+}
+
+func featureNames() []string {
+	names := make([]string, 0, len(featureDefaults))
+	for name := range featureDefaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
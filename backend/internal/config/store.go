@@ -0,0 +1,251 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"auth-go/internal/logger"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var configLog = logger.For("config")
+
+// ReloadHook prepares a component for a new configuration. Returning an
+// error rejects the configuration; otherwise the returned function, if any,
+// applies it once every hook has accepted it.
+type ReloadHook func(old *Config, next *Config) (apply func(), err error)
+
+// Store holds the configuration in use and reloads it from the same sources
+// it was loaded from. A reload only changes settings tagged reload:"true";
+// changes to the others are reported and wait for a restart.
+type Store struct {
+	opts    Options
+	current atomic.Pointer[snapshot]
+
+	// mu serialises reloads and guards hooks
+	mu    sync.Mutex
+	hooks []ReloadHook
+}
+
+type snapshot struct {
+	config   *Config
+	loadedAt time.Time
+}
+
+func NewStore(cfg *Config, opts Options) *Store {
+	s := &Store{opts: opts}
+	s.current.Store(&snapshot{config: cfg, loadedAt: time.Now()})
+	return s
+}
+
+// Current returns the configuration in use. It must not be modified.
+func (s *Store) Current() *Config {
+	return s.current.Load().config
+}
+
+// LoadedAt returns when the configuration in use was loaded
+func (s *Store) LoadedAt() time.Time {
+	return s.current.Load().loadedAt
+}
+
+// OnReload registers a hook run on every reload, in registration order
+func (s *Store) OnReload(hook ReloadHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Reload loads and validates the configuration again, lets the hooks check
+// it, then swaps it in. On error the configuration in use is kept.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded, err := Load(s.opts)
+	if err != nil {
+		return err
+	}
+	old := s.Current()
+	next := *old
+	changed, ignored := mergeReloadable(&next, loaded)
+	if len(ignored) > 0 {
+		configLog.Warn("Config changes need a restart and were ignored", "keys", strings.Join(ignored, ","))
+	}
+
+	// Hooks run even when nothing changed, e.g. to check edited email templates
+	var applies []func()
+	for _, hook := range s.hooks {
+		apply, err := hook(old, &next)
+		if err != nil {
+			return fmt.Errorf("configuration rejected: %w", err)
+		}
+		if apply != nil {
+			applies = append(applies, apply)
+		}
+	}
+
+	s.current.Store(&snapshot{config: &next, loadedAt: time.Now()})
+	for _, apply := range applies {
+		apply()
+	}
+	if len(changed) > 0 {
+		configLog.Info("Config reloaded", "changed", strings.Join(changed, ","))
+	} else {
+		configLog.Info("Config reloaded, no runtime settings changed")
+	}
+	return nil
+}
+
+// mergeReloadable copies the reloadable settings of loaded into next and
+// returns the keys that changed, along with changed keys that cannot
+// change at runtime
+func mergeReloadable(next *Config, loaded *Config) (changed []string, ignored []string) {
+	nv := reflect.ValueOf(next).Elem()
+	lv := reflect.ValueOf(loaded).Elem()
+	t := nv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if reflect.DeepEqual(nv.Field(i).Interface(), lv.Field(i).Interface()) {
+			continue
+		}
+		key := field.Tag.Get("mapstructure")
+		if field.Tag.Get("reload") != "true" {
+			ignored = append(ignored, key)
+			continue
+		}
+		nv.Field(i).Set(lv.Field(i))
+		changed = append(changed, key)
+	}
+	return changed, ignored
+}
+
+// File returns the config file the store reads, or "" when there is none
+func (s *Store) File() string {
+	path, _ := configFilePath(s.opts.File)
+	return path
+}
+
+// Watch reloads the configuration on SIGHUP and whenever the config file
+// changes, until ctx is done. Failed reloads are logged and keep the
+// configuration in use.
+func (s *Store) Watch(ctx context.Context) error {
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	file := s.File()
+	if file != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		// Watch the directory: editors and Kubernetes ConfigMaps replace the
+		// file instead of writing to it
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			watcher.Close()
+			return err
+		}
+		events, watchErrors = watcher.Events, watcher.Errors
+		go func() {
+			<-ctx.Done()
+			watcher.Close()
+		}()
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hangups)
+
+		// Wait for a burst of file events to settle before reloading
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+				configLog.Info("SIGHUP received, reloading config")
+				s.reloadAndLog()
+			case event, ok := <-events:
+				if !ok {
+					events = nil
+					continue
+				}
+				if s.affects(event, file) {
+					debounce.Reset(250 * time.Millisecond)
+				}
+			case err, ok := <-watchErrors:
+				if !ok {
+					watchErrors = nil
+					continue
+				}
+				configLog.Error("Watching config file failed", "file", file, "error", err)
+			case <-debounce.C:
+				configLog.Info("Config file changed, reloading", "file", file)
+				s.reloadAndLog()
+			}
+		}
+	}()
+	return nil
+}
+
+// affects reports whether a directory event may have changed the config
+// file, including Kubernetes' swap of its ..data symlink
+func (s *Store) affects(event fsnotify.Event, file string) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	return filepath.Clean(event.Name) == filepath.Clean(file) || name == "..data"
+}
+
+func (s *Store) reloadAndLog() {
+	if err := s.Reload(); err != nil {
+		configLog.Error("Config reload failed, keeping the current configuration", "error", err)
+	}
+}
+
+// redacted replaces the value of secret settings
+const redacted = "[REDACTED]"
+
+// Redacted returns every setting by key, with secrets masked, for display
+func (c *Config) Redacted() map[string]interface{} {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	settings := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		value := v.Field(i).Interface()
+		if isSecret(key) && !v.Field(i).IsZero() {
+			value = redacted
+		}
+		settings[key] = value
+	}
+	return settings
+}
+
+// ReloadableKeys returns the settings that can change at runtime
+func ReloadableKeys() []string {
+	t := reflect.TypeOf(Config{})
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("reload") == "true" {
+			keys = append(keys, t.Field(i).Tag.Get("mapstructure"))
+		}
+	}
+	return keys
+}
+
+func isSecret(key string) bool {
+	return strings.HasSuffix(key, "_SECRET") || strings.HasSuffix(key, "_PASSWORD") || strings.HasSuffix(key, "_TOKEN")
+}
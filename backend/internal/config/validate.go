@@ -0,0 +1,257 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth-go/internal/logger"
+	"auth-go/pkg/utils"
+)
+
+// minJWTSecretLength is 32 bytes, the size of the HS256 key
+const minJWTSecretLength = 32
+
+// minMetricsTokenLength keeps the metrics token from being guessed
+const minMetricsTokenLength = 16
+
+// ValidationError lists every problem found in a configuration, so they can
+// all be fixed in one go
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks required settings and the format of the others
+func (c *Config) Validate() error {
+	return c.validate(false)
+}
+
+// validate skips the mail settings when skipMail is set
+func (c *Config) validate(skipMail bool) error {
+	v := &validator{}
+
+	switch {
+	case c.JWTSecret == "":
+		v.addf("JWT_SECRET is required")
+	case len(c.JWTSecret) < minJWTSecretLength:
+		v.addf("JWT_SECRET must be at least %d characters", minJWTSecretLength)
+	}
+	if c.CursorSecret != "" && len(c.CursorSecret) < minJWTSecretLength {
+		v.addf("CURSOR_SECRET must be at least %d characters", minJWTSecretLength)
+	}
+
+	switch c.DBDriver {
+	case "mysql", "postgres":
+		v.required("DB_HOST", c.DBHost)
+		v.required("DB_USER", c.DBUser)
+		v.required("DB_NAME", c.DBName)
+		if c.DBPort != "" {
+			v.port("DB_PORT", c.DBPort)
+		}
+	case "sqlite":
+		v.required("DB_PATH", c.DBPath)
+	default:
+		v.addf("DB_DRIVER must be one of mysql, postgres, sqlite, got %q", c.DBDriver)
+	}
+	if c.DBDriver == "postgres" {
+		v.oneOf("DB_SSLMODE", c.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	}
+
+	if !skipMail {
+		v.oneOf("MAIL_TRANSPORT", c.MailTransport, "smtp", "file", "memory")
+		v.oneOf("DEV_MAIL_CAPTURE", c.DevMailCapture, "off", "on", "only")
+		v.oneOf("SMTP_TLS_MODE", c.SMTPTLSMode, "starttls", "tls")
+		// Capturing without delivering never connects to the SMTP server
+		captureOnly := c.GinMode != "release" && c.DevMailCapture == "only"
+		if c.MailTransport == "smtp" && !captureOnly {
+			v.required("SMTP_HOST", c.SMTPHost)
+			v.port("SMTP_PORT", strconv.Itoa(c.SMTPPort))
+		}
+		if c.MailTransport == "file" {
+			v.required("MAIL_FILE_DIR", c.MailFileDir)
+		}
+		v.duration("SMTP_IDLE_TIMEOUT", c.SMTPIdleTimeout, false)
+		v.nonNegative("SMTP_POOL_SIZE", int64(c.SMTPPoolSize))
+	}
+	v.required("EMAIL_DEFAULT_LOCALE", c.EmailDefaultLocale)
+	v.required("DEFAULT_LANGUAGE", c.DefaultLanguage)
+
+	if u, err := url.Parse(c.AppURL); err != nil || u.Scheme == "" || u.Host == "" {
+		v.addf("APP_URL must be an absolute URL, got %q", c.AppURL)
+	}
+
+	v.duration("JWT_EXPIRED_IN", c.JWTExpiredIn, true)
+	v.duration("OUTBOX_POLL_INTERVAL", c.OutboxPollInterval, false)
+	v.duration("OUTBOX_RETENTION", c.OutboxRetention, false)
+	v.duration("REQUEST_TIMEOUT", c.RequestTimeout, false)
+	v.duration("BULK_REQUEST_TIMEOUT", c.BulkRequestTimeout, false)
+	v.duration("EXPORT_TTL", c.ExportTTL, false)
+
+	v.nonNegative("OUTBOX_WORKERS", int64(c.OutboxWorkers))
+	v.nonNegative("OUTBOX_BATCH_SIZE", int64(c.OutboxBatchSize))
+	v.nonNegative("OUTBOX_MAX_ATTEMPTS", int64(c.OutboxMaxAttempts))
+	v.nonNegative("EXPORT_SYNC_MAX_ROWS", c.ExportSyncMaxRows)
+	v.nonNegative("EXPORT_MAX_JOBS", int64(c.ExportMaxJobs))
+	v.required("EXPORT_DIR", c.ExportDir)
+
+	v.origins("CORS_ALLOWED_ORIGINS", c.CORSAllowedOrigins)
+	v.origins("CORS_ADMIN_ALLOWED_ORIGINS", c.CORSAdminAllowedOrigins)
+	for _, directive := range strings.Split(c.CSPPolicy, ";") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(directive), " "); name != "" && !isDirectiveName(name) {
+			v.addf("CSP_POLICY has an invalid directive %q", name)
+		}
+	}
+	if c.CSPReportURI != "" && !strings.HasPrefix(c.CSPReportURI, "/") {
+		if u, err := url.Parse(c.CSPReportURI); err != nil || u.Scheme != "https" {
+			v.addf("CSP_REPORT_URI must be a path or an https URL, got %q", c.CSPReportURI)
+		}
+	}
+	v.oneOf("CROSS_ORIGIN_OPENER_POLICY", c.CrossOriginOpenerPolicy, "", "unsafe-none", "same-origin-allow-popups", "same-origin", "noopener-allow-popups")
+	v.oneOf("CROSS_ORIGIN_EMBEDDER_POLICY", c.CrossOriginEmbedderPolicy, "", "unsafe-none", "require-corp", "credentialless")
+	v.duration("HSTS_MAX_AGE", c.HSTSMaxAge, false)
+	for _, proxy := range c.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			v.addf("TRUSTED_PROXIES must list IP addresses or CIDRs, got %q", proxy)
+		}
+	}
+	v.rateLimit("RATE_LIMIT", c.RateLimitRPS, c.RateLimitBurst)
+	v.rateLimit("AUTH_RATE_LIMIT", c.AuthRateLimitRPS, c.AuthRateLimitBurst)
+	if _, err := c.Features(); err != nil {
+		v.addf("FEATURE_FLAGS: %v", err)
+	}
+	v.oneOf("LOG_LEVEL", c.LogLevel, "debug", "info", "warn", "error")
+	if err := logger.CheckLevels("", c.LogLevels); err != nil {
+		v.addf("LOG_LEVELS: %v", err)
+	}
+	v.oneOf("LOG_FORMAT", c.LogFormat, "json", "text")
+
+	if c.MetricsAddr != "" {
+		if _, port, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			v.addf("METRICS_ADDR must be a host:port such as 127.0.0.1:9090, got %q", c.MetricsAddr)
+		} else {
+			v.port("METRICS_ADDR", port)
+		}
+	}
+	if c.MetricsToken != "" && len(c.MetricsToken) < minMetricsTokenLength {
+		v.addf("METRICS_TOKEN must be at least %d characters", minMetricsTokenLength)
+	}
+
+	v.oneOf("TRACING_EXPORTER", c.TracingExporter, "none", "otlp", "stdout", "memory")
+	v.oneOf("TRACING_OTLP_PROTOCOL", c.TracingOTLPProtocol, "http/protobuf", "grpc")
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		v.addf("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.TracingSampleRatio)
+	}
+	if c.TracingExporter != "none" {
+		v.required("TRACING_SERVICE_NAME", c.TracingServiceName)
+	}
+	v.oneOf("HEALTH_SMTP_CHECK", c.HealthSMTPCheck, "off", "report", "require")
+	v.duration("SHUTDOWN_DELAY", c.ShutdownDelay, false)
+
+	v.port("PORT", c.Port)
+	v.oneOf("GIN_MODE", c.GinMode, "debug", "release", "test")
+	v.duration("HTTP_READ_HEADER_TIMEOUT", c.HTTPReadHeaderTimeout, false)
+	v.duration("HTTP_READ_TIMEOUT", c.HTTPReadTimeout, false)
+	v.duration("HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout, false)
+	v.duration("HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout, false)
+	v.duration("SHUTDOWN_TIMEOUT", c.ShutdownTimeout, false)
+	// A request cut off by the server cannot answer that it timed out
+	if write, err := time.ParseDuration(c.HTTPWriteTimeout); err == nil {
+		if request, err := time.ParseDuration(c.RequestTimeout); err == nil && write <= request {
+			v.addf("HTTP_WRITE_TIMEOUT (%s) must be longer than REQUEST_TIMEOUT (%s)", c.HTTPWriteTimeout, c.RequestTimeout)
+		}
+	}
+	v.nonNegative("HTTP_MAX_HEADER_BYTES", int64(c.HTTPMaxHeaderBytes))
+	v.nonNegative("MAX_BODY_BYTES", c.MaxBodyBytes)
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		v.addf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects problems instead of stopping at the first one
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf("%s is required", key)
+	}
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.addf("%s must be one of %s, got %q", key, strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) port(key string, value string) {
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		v.addf("%s must be a port number between 1 and 65535, got %q", key, value)
+	}
+}
+
+// duration checks a Go duration such as "15s" or "24h". Optional durations
+// may be empty to use the built-in default.
+func (v *validator) duration(key string, value string, required bool) {
+	if value == "" {
+		if required {
+			v.addf("%s is required", key)
+		}
+		return
+	}
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		v.addf("%s must be a positive duration such as 30s or 24h, got %q", key, value)
+	}
+}
+
+func (v *validator) origins(key string, value string) {
+	for _, origin := range splitList(value) {
+		if _, err := utils.ParseOriginPattern(origin); err != nil {
+			v.addf("%s: %v", key, err)
+		}
+	}
+}
+
+func isDirectiveName(name string) bool {
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// rateLimit checks a <prefix>_RPS and <prefix>_BURST pair
+func (v *validator) rateLimit(prefix string, rps float64, burst int) {
+	if rps < 0 {
+		v.addf("%s_RPS must not be negative", prefix)
+	}
+	if rps > 0 && burst < 1 {
+		v.addf("%s_BURST must be at least 1 when %s_RPS is set", prefix, prefix)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int64) {
+	if value < 0 {
+		v.addf("%s must not be negative", key)
+	}
+}
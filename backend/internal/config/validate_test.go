@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadSkipMail(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("JWT_SECRET", strings.Repeat("s", minJWTSecretLength))
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("MAIL_TRANSPORT", "smtp")
+	t.Setenv("DEV_MAIL_CAPTURE", "on")
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("SMTP_PORT", "")
+
+	_, err := Load(Options{})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), "SMTP_HOST") {
+		t.Fatalf("Load without SMTP settings = %v, want SMTP_HOST reported", err)
+	}
+
+	if _, err := Load(Options{SkipMail: true}); err != nil {
+		t.Fatalf("Load with SkipMail = %v, want no error", err)
+	}
+}
+
+func TestLoadCapturesMailWithoutSMTPOutsideRelease(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("JWT_SECRET", strings.Repeat("s", minJWTSecretLength))
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("MAIL_TRANSPORT", "smtp")
+	t.Setenv("DEV_MAIL_CAPTURE", "")
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("SMTP_PORT", "")
+
+	t.Setenv("GIN_MODE", "debug")
+	cfg, err := Load(Options{})
+	if err != nil {
+		t.Fatalf("Load in debug mode without SMTP settings = %v, want no error", err)
+	}
+	if cfg.DevMailCapture != "only" {
+		t.Fatalf("DEV_MAIL_CAPTURE = %q, want only by default", cfg.DevMailCapture)
+	}
+
+	t.Setenv("GIN_MODE", "release")
+	if _, err := Load(Options{}); err == nil || !strings.Contains(err.Error(), "SMTP_HOST") {
+		t.Fatalf("Load in release mode without SMTP settings = %v, want SMTP_HOST reported", err)
+	}
+}
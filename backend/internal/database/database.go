@@ -2,35 +2,125 @@ package database
 
 import (
 	"fmt"
-	"log"
+	"os"
+	"path/filepath"
 
 	"auth-go/internal/config"
-	"auth-go/internal/domain"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// Supported DB_DRIVER values, matching gorm's dialector names
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 func ConnectDB(cfg *config.Config) *gorm.DB {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBHost,
-		cfg.DBPort,
-		cfg.DBName,
-	)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := Open(cfg)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
+	}
+
+	if err := registerMetrics(db, dbName(cfg)); err != nil {
+		fatal("Failed to register database metrics", err)
+	}
+	if err := registerTracing(db); err != nil {
+		fatal("Failed to register database tracing", err)
+	}
+
+	if !cfg.DBAutoMigrate {
+		dbLog.Info("Database connected, automatic migrations disabled")
+		return db
 	}
 
-	// Auto Migrate
-	err = db.AutoMigrate(&domain.User{}, &domain.PasswordResetToken{})
+	// Apply pending versioned migrations
+	migrator, err := NewMigrator(db)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		fatal("Failed to load migrations", err)
+	}
+	applied, err := migrator.Up(0)
+	if err != nil {
+		fatal("Failed to migrate database", err)
 	}
 
-	log.Println("Database connected and migrated successfully")
+	dbLog.Info("Database connected and migrated", "migrations_applied", len(applied))
 	return db
 }
+
+// Open connects to the database of cfg, without the metrics, tracing and
+// migrations ConnectDB adds
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// TranslateError turns unique violations of every driver into
+	// gorm.ErrDuplicatedKey
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: queryLogger{}, TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.DBDriver == DriverSQLite {
+		// SQLite allows a single writer; one connection avoids SQLITE_BUSY
+		// errors and keeps ":memory:" databases shared across queries.
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+// fatal logs err and exits, for failures the API cannot start without
+func fatal(msg string, err error) {
+	dbLog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+func openDialector(cfg *config.Config) (gorm.Dialector, error) {
+	switch cfg.DBDriver {
+	case "", DriverMySQL:
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			cfg.DBUser,
+			cfg.DBPassword,
+			cfg.DBHost,
+			cfg.DBPort,
+			cfg.DBName,
+		)
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			cfg.DBHost,
+			cfg.DBPort,
+			cfg.DBUser,
+			cfg.DBPassword,
+			cfg.DBName,
+			cfg.DBSSLMode,
+		)
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		// DB_PATH may be a file path or ":memory:"
+		dsn := cfg.DBPath + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+		if cfg.DBPath != ":memory:" {
+			dsn += "&_pragma=journal_mode(WAL)"
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q (use mysql, postgres or sqlite)", cfg.DBDriver)
+	}
+}
+
+// dbName labels the connection pool metrics
+func dbName(cfg *config.Config) string {
+	if cfg.DBDriver == DriverSQLite {
+		return filepath.Base(cfg.DBPath)
+	}
+	return cfg.DBName
+}
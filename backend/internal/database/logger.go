@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"auth-go/internal/logger"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+var dbLog = logger.For("database")
+
+// slowQueryThreshold is the duration above which queries are logged as warnings
+const slowQueryThreshold = 200 * time.Millisecond
+
+// queryLogger writes GORM's logs through the "database" log component:
+// every statement at debug level, slow ones as warnings and failures as
+// errors. Statements are logged with placeholders instead of values, which
+// may hold passwords, tokens or personal data.
+type queryLogger struct {
+	// silent is set by LogMode(Silent), e.g. for sessions that expect errors
+	silent bool
+}
+
+func (l queryLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	return queryLogger{silent: level == gormlogger.Silent}
+}
+
+func (l queryLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if !l.silent {
+		dbLog.InfoContext(ctx, msg, "data", data)
+	}
+}
+
+func (l queryLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if !l.silent {
+		dbLog.WarnContext(ctx, msg, "data", data)
+	}
+}
+
+func (l queryLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if !l.silent {
+		dbLog.ErrorContext(ctx, msg, "data", data)
+	}
+}
+
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	duration := float64(elapsed.Microseconds()) / 1000
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && !errors.Is(err, context.Canceled):
+		sql, rows := fc()
+		dbLog.ErrorContext(ctx, "Query failed", "error", err, "sql", sql, "rows", rows, "duration_ms", duration)
+	case elapsed > slowQueryThreshold && dbLog.Enabled(ctx, slog.LevelWarn):
+		sql, rows := fc()
+		dbLog.WarnContext(ctx, "Slow query", "sql", sql, "rows", rows, "duration_ms", duration)
+	case dbLog.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		dbLog.DebugContext(ctx, "Query", "sql", sql, "rows", rows, "duration_ms", duration)
+	}
+}
+
+// ParamsFilter drops the values of statements before they are logged
+func (l queryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
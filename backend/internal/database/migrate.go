@@ -0,0 +1,419 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations live in one directory per driver (mysql, postgres, sqlite)
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// MigrationsDir is where `migrate create` writes new migration files,
+// relative to the backend module root
+const MigrationsDir = "internal/database/migrations"
+
+const (
+	migrationLockName = "auth_go_schema_migrations"
+	// migrationLockKey is the pg_advisory_lock key (crc32 of migrationLockName)
+	migrationLockKey = 3165193041
+)
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// SchemaMigration is a row of the schema_migrations table
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Checksum  string    `gorm:"type:char(64);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// MigrationStatus describes a known migration and whether it is applied
+type MigrationStatus struct {
+	Version          int64
+	Name             string
+	Applied          bool
+	AppliedAt        *time.Time
+	ChecksumMismatch bool
+}
+
+// Migrator applies the SQL migrations embedded in the binary
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, "migrations/"+db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db, migrations}, nil
+}
+
+// WithContext returns a migrator running its statements with ctx
+func (m *Migrator) WithContext(ctx context.Context) *Migrator {
+	return &Migrator{m.db.WithContext(ctx), m.migrations}
+}
+
+// Up applies pending migrations in order. steps <= 0 applies all of them.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(func(conn *gorm.DB) error {
+		done, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verifyChecksums(done); err != nil {
+			return err
+		}
+		if err := m.adoptLegacySchema(conn); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if steps > 0 && len(applied) >= steps {
+				break
+			}
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			err := m.exec(conn, migration.Up, func(tx *gorm.DB) error {
+				return tx.Create(&SchemaMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					Checksum:  migration.Checksum,
+					AppliedAt: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the most recently applied migrations. steps <= 0 reverts one.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var reverted []Migration
+
+	err := m.withLock(func(conn *gorm.DB) error {
+		done, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+			}
+
+			err := m.exec(conn, migration.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&SchemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration together with its applied state
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTable(m.db); err != nil {
+		return nil, err
+	}
+	done, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.ChecksumMismatch = row.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the number of migrations that are not applied yet
+func (m *Migrator) Pending() (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withLock runs fn on a single pinned connection while holding an advisory
+// lock, so concurrently starting replicas apply migrations once.
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		switch m.db.Dialector.Name() {
+		case DriverMySQL:
+			var acquired int
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, 300).Scan(&acquired).Error; err != nil {
+				return err
+			}
+			if acquired != 1 {
+				return errors.New("timed out waiting for the migration lock")
+			}
+			defer conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName)
+		case DriverPostgres:
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		}
+		// SQLite serializes writers on the database file, no extra lock needed
+
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// legacyColumns are created by the first migrations but missing from tables
+// set up by the AutoMigrate of earlier releases, which CREATE TABLE IF NOT
+// EXISTS keeps as they are
+var legacyColumns = []struct {
+	table  string
+	column string
+	// add maps drivers to the statement adding the column
+	add map[string]string
+}{
+	{
+		table:  "users",
+		column: "locale",
+		add: map[string]string{
+			DriverMySQL:    "ALTER TABLE users ADD COLUMN locale VARCHAR(16) NOT NULL DEFAULT 'en' AFTER password",
+			DriverPostgres: "ALTER TABLE users ADD COLUMN locale VARCHAR(16) NOT NULL DEFAULT 'en'",
+			DriverSQLite:   "ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT 'en'",
+		},
+	},
+}
+
+// adoptLegacySchema adds the legacyColumns missing from existing tables, so
+// databases created before the SQL migrations match the schema they describe
+func (m *Migrator) adoptLegacySchema(conn *gorm.DB) error {
+	schema := conn.Migrator()
+	for _, legacy := range legacyColumns {
+		if !schema.HasTable(legacy.table) || schema.HasColumn(legacy.table, legacy.column) {
+			continue
+		}
+		if err := conn.Exec(legacy.add[conn.Dialector.Name()]).Error; err != nil {
+			return fmt.Errorf("adding %s.%s to the existing table failed: %w", legacy.table, legacy.column, err)
+		}
+		dbLog.Info("Existing table adopted", "table", legacy.table, "column_added", legacy.column)
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	return conn.AutoMigrate(&SchemaMigration{})
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int64]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := conn.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
+
+// verifyChecksums refuses to continue when an applied migration was edited afterwards
+func (m *Migrator) verifyChecksums(done map[int64]SchemaMigration) error {
+	var mismatched []string
+	for _, migration := range m.migrations {
+		if row, ok := done[migration.Version]; ok && row.Checksum != migration.Checksum {
+			mismatched = append(mismatched, fmt.Sprintf("%d_%s", migration.Version, migration.Name))
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("applied migrations were modified: %s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// exec runs the statements of a script and records the result in one
+// transaction. MySQL commits DDL implicitly, so keep one change per file;
+// PostgreSQL and SQLite roll back the whole file on failure.
+func (m *Migrator) exec(conn *gorm.DB, script string, record func(tx *gorm.DB) error) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// CreateMigration writes an empty up/down pair into the directory of every
+// driver under dir, sharing one version, and returns the created paths
+func CreateMigration(dir string, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+
+	var created []string
+	for _, driver := range []string{DriverMySQL, DriverPostgres, DriverSQLite} {
+		driverDir := filepath.Join(dir, driver)
+		if err := os.MkdirAll(driverDir, 0o755); err != nil {
+			return created, err
+		}
+
+		up := filepath.Join(driverDir, version+"_"+name+".up.sql")
+		down := filepath.Join(driverDir, version+"_"+name+".down.sql")
+
+		if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+			return created, err
+		}
+		if err := os.WriteFile(down, []byte("-- revert "+name+"\n"), 0o644); err != nil {
+			return created, err
+		}
+		created = append(created, up, down)
+	}
+	return created, nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, dir+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a script on semicolons at the end of a line and
+// drops comment-only lines
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// legacyUser is the users table as the baseline AutoMigrate created it
+type legacyUser struct {
+	ID              uint64 `gorm:"primaryKey;autoIncrement"`
+	Name            string `gorm:"type:varchar(255);not null;index"`
+	Email           string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Password        string `gorm:"type:varchar(255);not null"`
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (legacyUser) TableName() string { return "users" }
+
+func TestMigratorUpFreshDatabase(t *testing.T) {
+	db := openTest(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up(0)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrator.migrations))
+	}
+	if pending, err := migrator.Pending(); err != nil || pending != 0 {
+		t.Fatalf("Pending = %d, %v; want 0", pending, err)
+	}
+
+	applied, err = migrator.Up(0)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("second Up applied %d migrations, %v; want none", len(applied), err)
+	}
+}
+
+func TestMigratorUpAdoptsAutoMigratedUsers(t *testing.T) {
+	db := openTest(t)
+	if err := db.AutoMigrate(&legacyUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&legacyUser{Name: "Ana", Email: "ana@example.com", Password: "hash"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	if !db.Migrator().HasColumn("users", "locale") {
+		t.Fatal("users.locale was not added")
+	}
+	var locale string
+	if err := db.Raw("SELECT locale FROM users WHERE email = ?", "ana@example.com").Scan(&locale).Error; err != nil {
+		t.Fatal(err)
+	}
+	if locale != "en" {
+		t.Fatalf("locale = %q, want %q", locale, "en")
+	}
+}
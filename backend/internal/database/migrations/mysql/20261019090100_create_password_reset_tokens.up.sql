@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    email VARCHAR(191) NOT NULL,
+    token VARCHAR(191) NOT NULL,
+    expires_at DATETIME(3) NULL,
+    created_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_password_reset_tokens_email (email),
+    INDEX idx_password_reset_tokens_token (token)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
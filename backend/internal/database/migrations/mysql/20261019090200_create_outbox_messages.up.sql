@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    kind VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    available_at DATETIME(3) NOT NULL,
+    locked_until DATETIME(3) NULL,
+    sent_at DATETIME(3) NULL,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_outbox_status_available (status, available_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+-- Keyset pagination orders by (created_at, id); InnoDB appends the primary key
+CREATE INDEX idx_users_created_at ON users (created_at);
@@ -0,0 +1,6 @@
+ALTER TABLE users
+    DROP INDEX idx_users_email_verified_at,
+    DROP INDEX idx_users_status,
+    DROP INDEX idx_users_role,
+    DROP COLUMN status,
+    DROP COLUMN role;
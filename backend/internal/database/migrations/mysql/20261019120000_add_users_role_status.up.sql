@@ -0,0 +1,6 @@
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user' AFTER locale,
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active' AFTER role,
+    ADD INDEX idx_users_role (role),
+    ADD INDEX idx_users_status (status),
+    ADD INDEX idx_users_email_verified_at (email_verified_at);
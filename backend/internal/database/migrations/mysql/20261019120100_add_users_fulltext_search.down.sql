@@ -0,0 +1 @@
+DROP INDEX ft_users_name_email ON users;
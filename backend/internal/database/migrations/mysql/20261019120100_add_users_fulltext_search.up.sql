@@ -0,0 +1,2 @@
+-- Enables search_mode=fulltext (and makes it the default for search_mode=auto)
+CREATE FULLTEXT INDEX ft_users_name_email ON users (name, email);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id VARCHAR(64) NOT NULL,
+    requested_by BIGINT UNSIGNED NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    params TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    row_count BIGINT NOT NULL DEFAULT 0,
+    file_path VARCHAR(512) NULL,
+    error TEXT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    completed_at DATETIME(3) NULL,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_export_jobs_requested_by (requested_by),
+    INDEX idx_export_jobs_status (status),
+    INDEX idx_export_jobs_expires_at (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN organization VARCHAR(100) NOT NULL DEFAULT '' AFTER status,
+    ADD INDEX idx_users_organization (organization);
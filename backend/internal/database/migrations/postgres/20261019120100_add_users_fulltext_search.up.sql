@@ -0,0 +1,3 @@
+-- The expression must match postgresSearchVector in the user repository
+CREATE INDEX IF NOT EXISTS idx_users_search_tsv ON users
+    USING gin (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(email, '')));
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id VARCHAR(64) PRIMARY KEY,
+    requested_by BIGINT NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    params TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    row_count BIGINT NOT NULL DEFAULT 0,
+    file_path VARCHAR(512) NULL,
+    error TEXT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NULL,
+    updated_at TIMESTAMPTZ NULL
+);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_requested_by ON export_jobs (requested_by);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_status ON export_jobs (status);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at ON export_jobs (expires_at);
@@ -0,0 +1,2 @@
+-- Keyset pagination orders by (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_email_verified_at;
+DROP INDEX IF EXISTS idx_users_status;
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP COLUMN status;
+ALTER TABLE users DROP COLUMN role;
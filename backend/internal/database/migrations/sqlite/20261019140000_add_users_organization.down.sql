@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_users_organization;
+ALTER TABLE users DROP COLUMN organization;
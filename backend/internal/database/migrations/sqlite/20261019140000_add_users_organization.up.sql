@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN organization TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_users_organization ON users (organization);
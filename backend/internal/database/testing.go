@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+
+	"auth-go/internal/config"
+
+	"gorm.io/gorm"
+)
+
+// OpenTest opens an in-memory SQLite database with every migration applied,
+// for tests. It is closed when the test ends.
+func OpenTest(t testing.TB) *gorm.DB {
+	t.Helper()
+	db := openTest(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	return db
+}
+
+// openTest opens an empty in-memory SQLite database, for the tests of the
+// migrations themselves
+func openTest(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := Open(&config.Config{DBDriver: DriverSQLite, DBPath: ":memory:"})
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
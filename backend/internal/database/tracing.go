@@ -0,0 +1,76 @@
+package database
+
+import (
+	"errors"
+
+	"auth-go/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var dbTracer = tracing.Tracer("database")
+
+// querySpanKey holds the span of a statement in gorm's instance values
+const querySpanKey = "tracing:query_span"
+
+// registerTracing starts a client span for every statement run through db,
+// a child of the span in the statement's context. Statements are recorded
+// with placeholders, like in the logs.
+func registerTracing(db *gorm.DB) error {
+	system := attribute.String(string(semconv.DBSystemNameKey), db.Dialector.Name())
+
+	before := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			ctx, span := dbTracer.Start(tx.Statement.Context, "gorm."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(system, semconv.DBOperationName(operation)),
+			)
+			tx.Statement.Context = ctx
+			tx.InstanceSet(querySpanKey, span)
+		}
+	}
+	after := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			value, ok := tx.InstanceGet(querySpanKey)
+			if !ok {
+				return
+			}
+			span := value.(trace.Span)
+			defer span.End()
+
+			// The table is only known once the statement is built
+			if table := tx.Statement.Table; table != "" {
+				span.SetName("gorm." + operation + " " + table)
+				span.SetAttributes(semconv.DBCollectionName(table))
+			}
+			span.SetAttributes(
+				semconv.DBQueryText(tx.Statement.SQL.String()),
+				attribute.Int64("db.response.affected_rows", tx.Statement.RowsAffected),
+			)
+			if err := tx.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", before("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", after("create")),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", before("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", after("query")),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", before("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", after("update")),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", after("delete")),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", before("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", after("row")),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", after("raw")),
+	)
+}
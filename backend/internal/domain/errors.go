@@ -0,0 +1,67 @@
+package domain
+
+import "strings"
+
+// ErrorKind classifies domain errors; the HTTP layer maps each kind to a
+// status code
+type ErrorKind string
+
+const (
+	ErrorKindValidation   ErrorKind = "validation"
+	ErrorKindUnauthorized ErrorKind = "unauthorized"
+	ErrorKindForbidden    ErrorKind = "forbidden"
+	ErrorKindNotFound     ErrorKind = "not_found"
+	ErrorKindConflict     ErrorKind = "conflict"
+	ErrorKindRateLimited  ErrorKind = "rate_limited"
+)
+
+// Error is an error meant for clients. Code is stable and machine readable,
+// the message may change. Wrapping an Error with fmt.Errorf("%w: ...") adds
+// detail while keeping its kind and code.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// Fields lists the invalid input fields of validation errors
+	Fields []FieldError
+}
+
+// FieldError is an invalid input field. Code is the failed rule, such as
+// "required" or "min", and Param its argument.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func NewError(kind ErrorKind, code string, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// NewValidationError reports invalid input fields. The message lists them,
+// e.g. "password must be at least 6 characters long".
+func NewValidationError(fields ...FieldError) *Error {
+	problems := make([]string, 0, len(fields))
+	for _, field := range fields {
+		problems = append(problems, field.Field+" "+field.Message)
+	}
+	message := "request validation failed"
+	if len(problems) > 0 {
+		message = strings.Join(problems, "; ")
+	}
+	return &Error{Kind: ErrorKindValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errors of the auth and user flows
+var (
+	ErrEmailTaken         = NewError(ErrorKindConflict, "email_taken", "email already registered")
+	ErrInvalidCredentials = NewError(ErrorKindUnauthorized, "invalid_credentials", "invalid email or password")
+	ErrInvalidResetToken  = NewError(ErrorKindValidation, "invalid_reset_token", "invalid or expired token")
+	ErrResetTokenExpired  = NewError(ErrorKindValidation, "reset_token_expired", "token expired")
+	ErrUserNotFound       = NewError(ErrorKindNotFound, "user_not_found", "user not found")
+)
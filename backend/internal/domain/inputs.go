@@ -5,6 +5,7 @@ type RegisterInput struct {
 	Name     string `json:"name" binding:"required,min=2"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	Locale   string `json:"locale" binding:"omitempty,bcp47_language_tag"`
 }
 
 // LoginInput validation struct
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Outbox message statuses
+const (
+	OutboxStatusPending    = "pending"
+	OutboxStatusProcessing = "processing"
+	OutboxStatusSent       = "sent"
+	OutboxStatusFailed     = "failed"
+)
+
+// OutboxStatuses lists every outbox message status
+var OutboxStatuses = []string{OutboxStatusPending, OutboxStatusProcessing, OutboxStatusSent, OutboxStatusFailed}
+
+// Outbox message kinds
+const (
+	OutboxKindWelcomeEmail       = "email.welcome"
+	OutboxKindResetPasswordEmail = "email.reset_password"
+	OutboxKindInvitationEmail    = "email.invitation"
+)
+
+// OutboxMessage entity
+//
+// Rows are written in the same transaction as the domain change that produced
+// them (see TxManager) and drained asynchronously by the outbox worker.
+type OutboxMessage struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Kind        string     `gorm:"type:varchar(100);not null" json:"kind"`
+	Payload     string     `gorm:"type:text;not null" json:"payload"`
+	Status      string     `gorm:"type:varchar(20);not null;default:pending;index:idx_outbox_status_available,priority:1" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	AvailableAt time.Time  `gorm:"not null;index:idx_outbox_status_available,priority:2" json:"available_at"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	SentAt      *time.Time `json:"sent_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// WelcomeEmailPayload is the payload of an OutboxKindWelcomeEmail message
+type WelcomeEmailPayload struct {
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// ResetPasswordEmailPayload is the payload of an OutboxKindResetPasswordEmail message
+type ResetPasswordEmailPayload struct {
+	Email     string `json:"email"`
+	ResetLink string `json:"reset_link"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// InvitationEmailPayload is the payload of an OutboxKindInvitationEmail message
+type InvitationEmailPayload struct {
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	InviteLink string `json:"invite_link"`
+	Locale     string `json:"locale,omitempty"`
+}
+
+// NewOutboxMessage builds a pending message with a JSON encoded payload
+func NewOutboxMessage(kind string, payload interface{}) (*OutboxMessage, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxMessage{
+		Kind:        kind,
+		Payload:     string(body),
+		Status:      OutboxStatusPending,
+		AvailableAt: time.Now(),
+	}, nil
+}
+
+// OutboxRepository interface
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, messages ...*OutboxMessage) error
+	// Claim locks up to limit due messages for the given lease and returns them.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	// MarkSent records the delivery and empties the payload, which may hold
+	// reset links and other secrets.
+	MarkSent(ctx context.Context, id uint64) error
+	// MarkRetry records a failed attempt and schedules the message again at next.
+	MarkRetry(ctx context.Context, id uint64, attempts int, lastError string, next time.Time) error
+	// MarkFailed records a failed attempt and gives up on the message.
+	MarkFailed(ctx context.Context, id uint64, attempts int, lastError string) error
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+	// PurgeSent deletes messages sent before the given time and returns how
+	// many were deleted.
+	PurgeSent(ctx context.Context, before time.Time) (int64, error)
+}
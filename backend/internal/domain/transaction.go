@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+// Repos groups the repositories that take part in a unit of work. Inside
+// TxManager.WithinTx they are bound to the ambient transaction.
+type Repos struct {
+	Users          UserRepository
+	PasswordResets PasswordResetRepository
+	Outbox         OutboxRepository
+}
+
+// TxManager runs several repository operations atomically
+type TxManager interface {
+	// WithinTx commits when fn returns nil and rolls back otherwise
+	WithinTx(ctx context.Context, fn func(tx Repos) error) error
+}
@@ -1,18 +1,38 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// User account statuses
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+)
+
 // User entity
 type User struct {
 	ID              uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name            string     `gorm:"type:varchar(255);not null;index" json:"name"`
 	Email           string     `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
 	Password        string     `gorm:"type:varchar(255);not null" json:"-"`
+	Locale          string     `gorm:"type:varchar(16);not null;default:en" json:"locale"`
+	Role            string     `gorm:"type:varchar(20);not null;default:user;index" json:"role"`
+	Status          string     `gorm:"type:varchar(20);not null;default:active;index" json:"status"`
+	Organization    string     `gorm:"type:varchar(100);not null;default:'';index" json:"organization,omitempty"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Highlight holds search matches per field wrapped in <mark>, only set in search results
+	Highlight map[string]string `gorm:"-" json:"highlight,omitempty"`
 }
 
 // PasswordResetToken entity
@@ -26,16 +46,27 @@ type PasswordResetToken struct {
 
 // UserRepository interface (Contract)
 type UserRepository interface {
-	Save(user *User) (*User, error)
-	FindByEmail(email string) (*User, error)
-	FindByID(id uint64) (*User, error)
-	FindAll(page int, limit int, search string) ([]*User, int64, error)
-	Update(user *User) (*User, error)
+	Save(ctx context.Context, user *User) (*User, error)
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByID(ctx context.Context, id uint64) (*User, error)
+	// FindByEmails returns the users among emails that exist, in no particular order
+	FindByEmails(ctx context.Context, emails []string) ([]*User, error)
+	// List returns a keyset page, see UserListQuery
+	List(ctx context.Context, q UserListQuery) (*UserPage, error)
+	// Count returns the number of users matching the search and filter of q
+	Count(ctx context.Context, q UserListQuery) (int64, error)
+	// Stream calls fn with consecutive batches of the users matching the
+	// search and filter of q, in id order, until fn returns an error
+	Stream(ctx context.Context, q UserListQuery, batchSize int, fn func(users []*User) error) error
+	Update(ctx context.Context, user *User) (*User, error)
 }
 
 // PasswordResetRepository interface
 type PasswordResetRepository interface {
-	Save(reset *PasswordResetToken) (*PasswordResetToken, error)
-	FindByToken(token string) (*PasswordResetToken, error)
-	DeleteByEmail(email string) error
+	Save(ctx context.Context, reset *PasswordResetToken) (*PasswordResetToken, error)
+	FindByToken(ctx context.Context, token string) (*PasswordResetToken, error)
+	// DeleteByToken removes token and returns 1, or 0 when it was already gone
+	DeleteByToken(ctx context.Context, token string) (int64, error)
+	// DeleteByEmail removes the tokens of email and returns how many there were
+	DeleteByEmail(ctx context.Context, email string) (int64, error)
 }
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Export job statuses
+const (
+	ExportJobPending = "pending"
+	ExportJobRunning = "running"
+	ExportJobDone    = "done"
+	ExportJobFailed  = "failed"
+)
+
+// UserExportColumns are the user fields that can be exported, in their
+// default order. The password hash is never exported.
+var UserExportColumns = []string{"id", "name", "email", "locale", "role", "status", "organization", "email_verified_at", "created_at", "updated_at"}
+
+// ErrInvalidExport is wrapped by errors about unsupported export options
+var ErrInvalidExport = NewError(ErrorKindValidation, "invalid_export", "invalid export")
+
+// ErrExportJobNotFound is returned for unknown or expired export jobs, and
+// for jobs requested by someone else
+var ErrExportJobNotFound = NewError(ErrorKindNotFound, "export_not_found", "export not found")
+
+// ErrExportJobNotReady is returned when downloading a job that has not
+// finished successfully
+var ErrExportJobNotReady = NewError(ErrorKindConflict, "export_not_ready", "export is not ready")
+
+// UserExportParams are the export options as received from the client. The
+// filters are those of the user list; its sort and paging fields are ignored.
+type UserExportParams struct {
+	// Format is csv, jsonl or xlsx
+	Format string `json:"format"`
+	// Columns is a comma separated subset of UserExportColumns
+	Columns string         `json:"columns,omitempty"`
+	Filters UserListParams `json:"filters"`
+}
+
+// ExportJob entity
+//
+// Large exports are written to a file in the background and can be
+// downloaded until ExpiresAt, after which the file and the row are removed.
+type ExportJob struct {
+	ID          string     `gorm:"primaryKey;type:varchar(64)" json:"id"`
+	RequestedBy uint64     `gorm:"not null;index" json:"requested_by"`
+	Format      string     `gorm:"type:varchar(10);not null" json:"format"`
+	Params      string     `gorm:"type:text;not null" json:"-"`
+	Status      string     `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	RowCount    int64      `gorm:"not null;default:0" json:"rows"`
+	FilePath    string     `gorm:"type:varchar(512)" json:"-"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	ExpiresAt   time.Time  `gorm:"not null;index" json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ExportJobRepository interface
+type ExportJobRepository interface {
+	Create(ctx context.Context, job *ExportJob) error
+	FindByID(ctx context.Context, id string) (*ExportJob, error)
+	Update(ctx context.Context, job *ExportJob) error
+	// FailUnfinished marks jobs left pending or running by a previous process as failed
+	FailUnfinished(ctx context.Context, reason string) (int64, error)
+	// FindExpired returns jobs whose download expired before now
+	FindExpired(ctx context.Context, now time.Time, limit int) ([]*ExportJob, error)
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,77 @@
+package domain
+
+// Import modes, deciding what happens to rows whose email already exists
+const (
+	// ImportModeInsert reports existing emails as row errors
+	ImportModeInsert = "insert"
+	// ImportModeSkip leaves existing users untouched
+	ImportModeSkip = "skip"
+	// ImportModeUpsert updates existing users with the imported fields
+	ImportModeUpsert = "upsert"
+)
+
+// Import file formats
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// ErrInvalidImport is wrapped by errors that reject the import as a whole,
+// such as unknown options or a malformed CSV header
+var ErrInvalidImport = NewError(ErrorKindValidation, "invalid_import", "invalid import")
+
+// UserImportRecord is one row of an import file. CSV headers and JSON keys
+// use the json tag names.
+type UserImportRecord struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// PasswordHash is a bcrypt hash from a legacy system, imported as is
+	PasswordHash string `json:"password_hash"`
+	Locale       string `json:"locale"`
+	Role         string `json:"role"`
+}
+
+// UserImportOptions control an import run
+type UserImportOptions struct {
+	Format string
+	Mode   string
+	// DryRun validates every row and reports what would happen without writing
+	DryRun bool
+	// Invite emails created users a link to set their password
+	Invite bool
+	// MaxErrors caps the row errors kept in the report, 0 keeps all of them
+	MaxErrors int
+}
+
+// UserImportFieldError is a problem with one field of a row. Field is empty
+// for problems with the row as a whole.
+type UserImportFieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// UserImportRowError lists the problems of a rejected row
+type UserImportRowError struct {
+	// Row is the line number in the file
+	Row    int                    `json:"row"`
+	Email  string                 `json:"email,omitempty"`
+	Errors []UserImportFieldError `json:"errors"`
+}
+
+// UserImportReport summarises an import run
+type UserImportReport struct {
+	Format  string `json:"format"`
+	Mode    string `json:"mode"`
+	DryRun  bool   `json:"dry_run"`
+	Total   int    `json:"total"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+	Skipped int    `json:"skipped"`
+	Failed  int    `json:"failed"`
+	Invited int    `json:"invited"`
+
+	Errors []UserImportRowError `json:"errors"`
+	// ErrorsTruncated is set when more rows failed than MaxErrors
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
@@ -0,0 +1,128 @@
+package domain
+
+import "time"
+
+// Sortable user list fields
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortName      = "name"
+	UserSortEmail     = "email"
+	// UserSortRelevance orders search results by match score
+	UserSortRelevance = "relevance"
+)
+
+// Search modes
+const (
+	// SearchAuto uses full-text search when an index exists, prefix otherwise
+	SearchAuto = "auto"
+	// SearchPrefix matches names and emails starting with the term
+	SearchPrefix = "prefix"
+	// SearchFullText matches words via MySQL FULLTEXT or PostgreSQL tsvector
+	SearchFullText = "fulltext"
+	// SearchFuzzy tolerates typos (pg_trgm similarity, SOUNDEX on MySQL)
+	SearchFuzzy = "fuzzy"
+)
+
+// Total count modes for the user list
+const (
+	// CountExact runs COUNT(*) over the filtered rows
+	CountExact = "exact"
+	// CountApprox uses table statistics when no filter is applied
+	CountApprox = "approx"
+	// CountNone skips counting entirely
+	CountNone = "none"
+)
+
+// ErrInvalidListQuery is wrapped by errors about unsupported list options
+var ErrInvalidListQuery = NewError(ErrorKindValidation, "invalid_list_query", "invalid list query")
+
+// ErrInvalidCursor is returned for cursors that are malformed, tampered
+// with, or were issued for a different sort order
+var ErrInvalidCursor = NewError(ErrorKindValidation, "invalid_cursor", "invalid or expired cursor")
+
+// UserCursor is the keyset position of a row in a sorted user listing.
+// It is handed to clients signed and opaque.
+type UserCursor struct {
+	Sort string `json:"s"`
+	Desc bool   `json:"d"`
+	// Value is the sort key of the row (RFC 3339 for created_at)
+	Value string `json:"v,omitempty"`
+	ID    uint64 `json:"i,omitempty"`
+	// Backward cursors page towards the start of the list
+	Backward bool `json:"b,omitempty"`
+	// Offset is used instead of a keyset for relevance ordering
+	Offset int `json:"o,omitempty"`
+}
+
+// UserFilter narrows the user list. Zero values do not filter.
+type UserFilter struct {
+	Status      string
+	Role        string
+	Verified    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// IsZero reports whether the filter matches every user
+func (f UserFilter) IsZero() bool {
+	return f.Status == "" && f.Role == "" && f.Verified == nil && f.CreatedFrom == nil && f.CreatedTo == nil
+}
+
+// UserListQuery selects one page of users
+type UserListQuery struct {
+	Search     string
+	SearchMode string
+	Filter     UserFilter
+	Sort       string
+	Desc       bool
+	Limit      int
+	// Cursor is nil for the first page
+	Cursor *UserCursor
+	// Offset selects a page by OFFSET when no cursor is given (legacy ?page=)
+	Offset int
+	Count  string
+}
+
+// UserPage is one page of users
+type UserPage struct {
+	Users       []*User
+	Total       int64
+	TotalApprox bool
+	// SearchMode is the mode actually used after fallbacks
+	SearchMode string
+	// Next and Prev are nil at the end and start of the list
+	Next *UserCursor
+	Prev *UserCursor
+}
+
+// UserListParams are the user list options as received from the client
+type UserListParams struct {
+	Search     string
+	SearchMode string
+	// Sort is a field name, prefixed with "-" for descending order
+	Sort   string
+	Limit  int
+	Page   int
+	Cursor string
+	Count  string
+
+	Status      string
+	Role        string
+	Verified    string
+	CreatedFrom string
+	CreatedTo   string
+}
+
+// UserListResult is a page of users with encoded cursors
+type UserListResult struct {
+	Users       []*User
+	Total       int64
+	TotalApprox bool
+	SearchMode  string
+	Sort        string
+	Limit       int
+	// Page is set when the page was selected with ?page=
+	Page int
+	Next string
+	Prev string
+}
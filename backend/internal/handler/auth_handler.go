@@ -2,7 +2,10 @@ package handler
 
 import (
 	"auth-go/internal/domain"
+	"auth-go/internal/i18n"
 	"auth-go/internal/service"
+	"context"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,68 +21,74 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var input domain.RegisterInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	user, err := h.authService.Register(&input)
+	// Emails go out in the language the user signed up in, unless chosen
+	if input.Locale == "" {
+		input.Locale = i18n.For(c.Request.Context()).Language()
+	}
+
+	user, err := h.authService.Register(c.Request.Context(), &input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"data": user})
+	c.JSON(http.StatusCreated, DataResponse[*domain.User]{user})
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var input domain.LoginInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	token, user, err := h.authService.Login(&input)
+	token, user, err := h.authService.Login(c.Request.Context(), &input)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token, "user": user})
+	c.JSON(http.StatusOK, LoginResponse{Token: token, User: user})
 }
 
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var input domain.ForgotPasswordInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	err := h.authService.ForgotPassword(&input)
+	err := h.authService.ForgotPassword(c.Request.Context(), &input)
 	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			respondError(c, err)
+			return
+		}
+
 		// Log the error internally
-		// log.Printf("Forgot password error: %v", err)
+		handlerLog.ErrorContext(c.Request.Context(), "Forgot password failed", "error", err)
 
 		// ALWAYS return success to prevent Email Enumeration attacks
-		c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a reset link."})
+		c.JSON(http.StatusOK, MessageResponse{i18n.For(c.Request.Context()).Text("messages.reset_link_sent", "If your email is registered, you will receive a reset link.")})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a reset link."})
+	c.JSON(http.StatusOK, MessageResponse{i18n.For(c.Request.Context()).Text("messages.reset_link_sent", "If your email is registered, you will receive a reset link.")})
 }
 
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var input domain.ResetPasswordInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
-	err := h.authService.ResetPassword(&input)
+	err := h.authService.ResetPassword(c.Request.Context(), &input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully."})
+	c.JSON(http.StatusOK, MessageResponse{i18n.For(c.Request.Context()).Text("messages.password_reset", "Password has been reset successfully.")})
 }
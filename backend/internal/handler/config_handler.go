@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"auth-go/internal/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+	store *config.Store
+}
+
+func NewConfigHandler(store *config.Store) *ConfigHandler {
+	return &ConfigHandler{store}
+}
+
+// Get returns the effective configuration with secrets redacted
+func (h *ConfigHandler) Get(c *gin.Context) {
+	cfg := h.store.Current()
+	features, _ := cfg.Features()
+	c.JSON(http.StatusOK, ConfigResponse{
+		Data: cfg.Redacted(),
+		Meta: ConfigMeta{
+			File:       h.store.File(),
+			LoadedAt:   h.store.LoadedAt(),
+			Reloadable: config.ReloadableKeys(),
+			Features:   features,
+		},
+	})
+}
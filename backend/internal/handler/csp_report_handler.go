@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"auth-go/internal/logger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCSPReportSize bounds a report body; real reports are a few kilobytes
+const maxCSPReportSize = 64 << 10
+
+// cspViolation holds the fields of a violation worth logging. The legacy
+// report-uri format uses dashed names, the Reporting API camel case.
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+
+	DocumentURL                string `json:"documentURL"`
+	BlockedURL                 string `json:"blockedURL"`
+	EffectiveDirectiveReported string `json:"effectiveDirective"`
+	SourceFileReported         string `json:"sourceFile"`
+	LineNumberReported         int    `json:"lineNumber"`
+}
+
+var cspLog = logger.For("csp")
+
+type CSPReportHandler struct{}
+
+func NewCSPReportHandler() *CSPReportHandler {
+	return &CSPReportHandler{}
+}
+
+// Collect logs Content-Security-Policy violation reports, in either the
+// application/csp-report format of report-uri or the
+// application/reports+json format of the Reporting API
+func (h *CSPReportHandler) Collect(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCSPReportSize))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var violations []cspViolation
+	if strings.HasPrefix(c.ContentType(), "application/reports+json") {
+		var reports []struct {
+			Type string       `json:"type"`
+			Body cspViolation `json:"body"`
+		}
+		err = json.Unmarshal(body, &reports)
+		for _, report := range reports {
+			if report.Type == "csp-violation" {
+				violations = append(violations, report.Body)
+			}
+		}
+	} else {
+		var report struct {
+			Body cspViolation `json:"csp-report"`
+		}
+		err = json.Unmarshal(body, &report)
+		violations = append(violations, report.Body)
+	}
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid_csp_report", "Invalid CSP report")
+		return
+	}
+
+	for _, v := range violations {
+		location := ""
+		if source := firstNonEmpty(v.SourceFile, v.SourceFileReported); source != "" {
+			location = fmt.Sprintf("%s:%d", source, max(v.LineNumber, v.LineNumberReported))
+		}
+		cspLog.WarnContext(c.Request.Context(), "CSP violation",
+			"disposition", firstNonEmpty(v.Disposition, "enforce"),
+			"directive", firstNonEmpty(v.EffectiveDirective, v.EffectiveDirectiveReported, v.ViolatedDirective),
+			"blocked", firstNonEmpty(v.BlockedURI, v.BlockedURL),
+			"document", firstNonEmpty(v.DocumentURI, v.DocumentURL),
+			"source", location)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
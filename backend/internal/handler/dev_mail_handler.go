@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"auth-go/internal/mail"
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DevMailHandler serves the messages captured by mail.CaptureTransport.
+// It must only be mounted in debug mode. Its CSP, which allows the inline
+// styles of email markup, is set in cmd/api.
+type DevMailHandler struct {
+	capture *mail.CaptureTransport
+}
+
+func NewDevMailHandler(capture *mail.CaptureTransport) *DevMailHandler {
+	return &DevMailHandler{capture}
+}
+
+var devMailListTemplate = template.Must(template.New("list").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>Captured mail</title>
+  <style>
+    body { font-family: Arial, Helvetica, sans-serif; margin: 32px; color: #18181b; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { text-align: left; padding: 8px 12px; border-bottom: 1px solid #e4e4e7; font-size: 14px; }
+    th { background: #f4f4f5; }
+    a { color: #2563eb; }
+  </style>
+</head>
+<body>
+  <h1>Captured mail</h1>
+  {{if .}}
+  <table>
+    <tr><th>Date</th><th>To</th><th>Subject</th><th></th></tr>
+    {{range .}}
+    <tr>
+      <td>{{.Date.Format "2006-01-02 15:04:05"}}</td>
+      <td>{{range $i, $to := .To}}{{if $i}}, {{end}}{{$to}}{{end}}</td>
+      <td><a href="/_dev/mail/{{.ID}}">{{.Subject}}</a></td>
+      <td><a href="/_dev/mail/{{.ID}}?format=text">text</a> &middot; <a href="/_dev/mail/{{.ID}}?format=raw">raw</a></td>
+    </tr>
+    {{end}}
+  </table>
+  {{else}}
+  <p>No messages captured yet.</p>
+  {{end}}
+</body>
+</html>
+`))
+
+func (h *DevMailHandler) List(c *gin.Context) {
+	messages := h.capture.List()
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, DataResponse[[]*mail.Message]{messages})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := devMailListTemplate.Execute(&buf, messages); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+func (h *DevMailHandler) Show(c *gin.Context) {
+	msg, ok := h.capture.Get(c.Param("id"))
+	if !ok {
+		respondProblem(c, http.StatusNotFound, "message_not_found", "Message not found")
+		return
+	}
+
+	switch c.Query("format") {
+	case "raw":
+		var buf bytes.Buffer
+		if _, err := msg.WriteTo(&buf); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(msg.Text))
+	default:
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(msg.HTML))
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"auth-go/internal/domain"
+	"auth-go/internal/i18n"
+	"auth-go/internal/logger"
+	"auth-go/internal/problem"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var handlerLog = logger.For("handler")
+
+// respondError answers err as problem details. Internal errors are logged
+// here, clients only get the request ID to quote.
+func respondError(c *gin.Context, err error) {
+	p := problem.FromError(c, err)
+	if p.Code == problem.CodeInternal {
+		handlerLog.ErrorContext(c.Request.Context(), "Request failed", "route", c.FullPath(), "error", err)
+	}
+	problem.Abort(c, p)
+}
+
+// respondProblem answers a problem that is not raised by an error
+func respondProblem(c *gin.Context, status int, code string, detail string) {
+	problem.Abort(c, problem.New(c, status, code, detail))
+}
+
+// bindJSON decodes and validates the JSON body into input. On failure it
+// answers with the invalid fields and returns false.
+func bindJSON(c *gin.Context, input interface{}) bool {
+	jsonFieldNames.Do(useJSONFieldNames)
+	if err := c.ShouldBindJSON(input); err != nil {
+		respondError(c, bindError(i18n.For(c.Request.Context()), err))
+		return false
+	}
+	return true
+}
+
+var jsonFieldNames sync.Once
+
+// useJSONFieldNames makes validation errors name fields as clients send
+// them, "password" rather than "RegisterInput.Password"
+func useJSONFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+}
+
+// bindError turns decoding and validation errors into domain errors, with
+// field messages in the language of loc
+func bindError(loc *i18n.Localizer, err error) error {
+	var invalid validator.ValidationErrors
+	var wrongType *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]domain.FieldError, 0, len(invalid))
+		for _, fieldErr := range invalid {
+			param := fieldErr.Param()
+			switch fieldErr.Tag() {
+			case "eqfield":
+				param = snakeCase(param)
+			case "oneof":
+				param = strings.ReplaceAll(param, " ", ", ")
+			}
+			fields = append(fields, domain.FieldError{
+				Field:   fieldErr.Field(),
+				Code:    fieldErr.Tag(),
+				Param:   param,
+				Message: loc.Rule(fieldErr.Tag(), param),
+			})
+		}
+		return domain.NewValidationError(fields...)
+	case errors.As(err, &wrongType):
+		return domain.NewValidationError(domain.FieldError{
+			Field:   wrongType.Field,
+			Code:    "type",
+			Param:   wrongType.Type.Kind().String(),
+			Message: loc.Rule("type", wrongType.Type.Kind().String()),
+		})
+	case errors.As(err, &tooLarge):
+		return err
+	case errors.Is(err, io.EOF):
+		return domain.NewError(domain.ErrorKindValidation, problem.CodeEmptyBody, "Request body is empty")
+	}
+	// Syntax errors and anything else the decoder rejects
+	return domain.NewError(domain.ErrorKindValidation, problem.CodeMalformedBody, "Request body is not valid JSON")
+}
+
+// snakeCase turns a Go field name into its JSON name, ConfirmPassword into
+// confirm_password
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// NotFound answers requests matching no route
+func NotFound(c *gin.Context) {
+	respondProblem(c, http.StatusNotFound, problem.CodeNotFound, "Route not found")
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"auth-go/internal/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker}
+}
+
+// Live answers as long as the process serves requests. It checks no
+// dependency, so an unreachable database never gets the API restarted.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, LiveResponse{Status: "ok"})
+}
+
+// Ready answers 503 while a critical dependency fails or the API shuts
+// down. Errors are left out, they are for admins only.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if h.checker.ShuttingDown() {
+		c.JSON(http.StatusServiceUnavailable, ReadyResponse{Status: health.StatusShuttingDown})
+		return
+	}
+
+	report := h.checker.Check(c.Request.Context())
+	checks := make(map[string]string, len(report.Checks))
+	for _, result := range report.Checks {
+		checks[result.Name] = result.Status
+	}
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, ReadyResponse{Status: report.Status, Checks: checks})
+}
+
+// Status returns every check with its error and details, for admins
+func (h *HealthHandler) Status(c *gin.Context) {
+	report := h.checker.Check(c.Request.Context())
+	c.JSON(http.StatusOK, HealthStatusResponse{
+		Data: report,
+		Meta: HealthMeta{
+			UptimeSeconds: int64(h.checker.Uptime().Seconds()),
+			ShuttingDown:  h.checker.ShuttingDown(),
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"auth-go/internal/middleware"
+	"auth-go/internal/openapi"
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// OpenAPIHandler serves the OpenAPI document and, in debug mode, Swagger UI.
+// The UI's CSP, which adds a nonce for its inline script, is set in cmd/api.
+type OpenAPIHandler struct {
+	document []byte
+}
+
+func NewOpenAPIHandler(doc *openapi.Document) (*OpenAPIHandler, error) {
+	document, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAPIHandler{document}, nil
+}
+
+var swaggerUITemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+  <link rel="icon" type="image/png" href="/docs/assets/favicon-32x32.png">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/assets/swagger-ui-bundle.js"></script>
+  <script nonce="{{.}}">
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui", validatorUrl: null, deepLinking: true });
+  </script>
+</body>
+</html>
+`))
+
+func (h *OpenAPIHandler) Document(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.document)
+}
+
+func (h *OpenAPIHandler) SwaggerUI(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := swaggerUITemplate.Execute(&buf, middleware.CSPNonce(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// Assets serves the Swagger UI scripts and styles embedded in the binary
+func (h *OpenAPIHandler) Assets(c *gin.Context) {
+	c.FileFromFS(c.Param("file"), http.FS(swaggerFiles.FS))
+}
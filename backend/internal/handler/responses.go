@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"auth-go/internal/domain"
+	"auth-go/internal/health"
+	"time"
+)
+
+// Response bodies of the handlers. The OpenAPI document is generated from
+// these types, so a field added here is documented too.
+
+// DataResponse wraps a single resource or list
+type DataResponse[T any] struct {
+	Data T `json:"data"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type LoginResponse struct {
+	Token string       `json:"token"`
+	User  *domain.User `json:"user"`
+}
+
+type UserListResponse struct {
+	Data []*domain.User `json:"data"`
+	Meta UserListMeta   `json:"meta"`
+}
+
+type UserListMeta struct {
+	Total            int64  `json:"total"`
+	TotalApproximate bool   `json:"total_approximate"`
+	Limit            int    `json:"limit"`
+	Sort             string `json:"sort"`
+	// Next and Prev are cursors for ?cursor=, null at either end
+	Next       *string `json:"next"`
+	Prev       *string `json:"prev"`
+	SearchMode string  `json:"search_mode,omitempty"`
+	// Page is set for the first page and pages selected with ?page=
+	Page int `json:"page,omitempty"`
+}
+
+type ExportJobResponse struct {
+	Data  *domain.ExportJob `json:"data"`
+	Links map[string]string `json:"links"`
+}
+
+type ConfigResponse struct {
+	Data map[string]interface{} `json:"data"`
+	Meta ConfigMeta             `json:"meta"`
+}
+
+type ConfigMeta struct {
+	File       string          `json:"file"`
+	LoadedAt   time.Time       `json:"loaded_at"`
+	Reloadable []string        `json:"reloadable"`
+	Features   map[string]bool `json:"features"`
+}
+
+type LiveResponse struct {
+	Status string `json:"status"`
+}
+
+type ReadyResponse struct {
+	Status string `json:"status"`
+	// Checks maps check names to their status, absent while shutting down
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type HealthStatusResponse struct {
+	Data *health.Report `json:"data"`
+	Meta HealthMeta     `json:"meta"`
+}
+
+type HealthMeta struct {
+	UptimeSeconds int64 `json:"uptime_seconds"`
+	ShuttingDown  bool  `json:"shutting_down"`
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"auth-go/internal/domain"
+	"auth-go/internal/export"
+	"auth-go/internal/service"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserExportHandler struct {
+	exportService service.UserExportService
+	syncMaxRows   int64
+}
+
+func NewUserExportHandler(exportService service.UserExportService, syncMaxRows int64) *UserExportHandler {
+	return &UserExportHandler{exportService, syncMaxRows}
+}
+
+// Export streams the users matching the user list filters as a file.
+// Exports over the configured row count, or any with ?async=true, run as a
+// background job instead and answer 202 with the job.
+func (h *UserExportHandler) Export(c *gin.Context) {
+	params := exportParams(c)
+	async, _ := strconv.ParseBool(c.Query("async"))
+
+	// Counting also validates the parameters before anything is written
+	count, err := h.exportService.Count(c.Request.Context(), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if async || (h.syncMaxRows > 0 && count > h.syncMaxRows) {
+		h.startJob(c, params)
+		return
+	}
+
+	format := params.Format
+	if format == "" {
+		format = export.FormatCSV
+	}
+	filename := fmt.Sprintf("users-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	if _, err := h.exportService.Export(c.Request.Context(), c.Writer, params); err != nil {
+		// The response has started, all we can do is cut it short
+		handlerLog.ErrorContext(c.Request.Context(), "Streaming export failed", "file", filename, "error", err)
+		c.Abort()
+	}
+}
+
+// CreateJob starts a background export regardless of its size
+func (h *UserExportHandler) CreateJob(c *gin.Context) {
+	params := exportParams(c)
+	if _, err := h.exportService.Count(c.Request.Context(), params); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.startJob(c, params)
+}
+
+func (h *UserExportHandler) GetJob(c *gin.Context) {
+	job, err := h.exportService.GetJob(c.Request.Context(), c.GetUint64("userID"), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ExportJobResponse{Data: job, Links: jobLinks(job)})
+}
+
+func (h *UserExportHandler) Download(c *gin.Context) {
+	job, path, err := h.exportService.OpenJob(c.Request.Context(), c.GetUint64("userID"), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	filename := fmt.Sprintf("users-%s.%s", job.CreatedAt.UTC().Format("20060102-150405"), job.Format)
+	c.Header("Content-Type", export.ContentType(job.Format))
+	c.FileAttachment(path, filename)
+}
+
+func (h *UserExportHandler) startJob(c *gin.Context, params domain.UserExportParams) {
+	job, err := h.exportService.StartJob(c.Request.Context(), c.GetUint64("userID"), params)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	links := jobLinks(job)
+	c.Header("Location", links["self"])
+	c.JSON(http.StatusAccepted, ExportJobResponse{Data: job, Links: links})
+}
+
+// exportParams reads the export options and the user list filters
+func exportParams(c *gin.Context) domain.UserExportParams {
+	return domain.UserExportParams{
+		Format:  c.Query("format"),
+		Columns: c.Query("columns"),
+		Filters: domain.UserListParams{
+			Search:      c.Query("search"),
+			SearchMode:  c.Query("search_mode"),
+			Status:      c.Query("status"),
+			Role:        c.Query("role"),
+			Verified:    c.Query("verified"),
+			CreatedFrom: c.Query("created_from"),
+			CreatedTo:   c.Query("created_to"),
+		},
+	}
+}
+
+func jobLinks(job *domain.ExportJob) map[string]string {
+	links := map[string]string{"self": "/api/admin/users/export/jobs/" + job.ID}
+	if job.Status == domain.ExportJobDone {
+		links["download"] = links["self"] + "/download"
+	}
+	return links
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"auth-go/internal/domain"
+	"auth-go/internal/problem"
 	"auth-go/internal/service"
 	"net/http"
 	"strconv"
@@ -19,38 +21,74 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		respondProblem(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
 		return
 	}
 
-	user, err := h.userService.GetProfile(userID.(uint64))
+	user, err := h.userService.GetProfile(c.Request.Context(), userID.(uint64))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": user})
+	c.JSON(http.StatusOK, DataResponse[*domain.User]{user})
 }
 
+// GetAllUsers lists users with keyset pagination: follow meta.next and
+// meta.prev by passing them back as ?cursor=. The legacy ?page=N still works
+// and continues with cursors from there.
+//
+// Search with ?search= (and ?search_mode=auto|prefix|fulltext|fuzzy), filter
+// with ?status=, ?role=, ?verified=, ?created_from= and ?created_to=.
+// Searches are ordered by relevance unless ?sort= is given.
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	search := c.Query("search")
 
-	users, total, err := h.userService.GetAllUsers(page, limit, search)
+	result, err := h.userService.ListUsers(c.Request.Context(), domain.UserListParams{
+		Search:      c.Query("search"),
+		SearchMode:  c.Query("search_mode"),
+		Sort:        c.Query("sort"),
+		Limit:       limit,
+		Page:        page,
+		Cursor:      c.Query("cursor"),
+		Count:       c.Query("count"),
+		Status:      c.Query("status"),
+		Role:        c.Query("role"),
+		Verified:    c.Query("verified"),
+		CreatedFrom: c.Query("created_from"),
+		CreatedTo:   c.Query("created_to"),
+	})
 	if err != nil {
-		// Log error internally, don't return raw error to client
-		// log.Println("Error fetching users:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": users,
-		"meta": gin.H{
-			"total": total,
-			"page":  page,
-			"limit": limit,
-		},
-	})
+	meta := UserListMeta{
+		Total:            result.Total,
+		TotalApproximate: result.TotalApprox,
+		Limit:            result.Limit,
+		Sort:             result.Sort,
+		Next:             nullableCursor(result.Next),
+		Prev:             nullableCursor(result.Prev),
+		SearchMode:       result.SearchMode,
+		Page:             result.Page,
+	}
+	// The first page is also page 1 for clients still using ?page=
+	if result.Page == 0 && c.Query("cursor") == "" {
+		meta.Page = 1
+	}
+
+	users := result.Users
+	if users == nil {
+		users = []*domain.User{}
+	}
+	c.JSON(http.StatusOK, UserListResponse{Data: users, Meta: meta})
+}
+
+func nullableCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
 }
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"auth-go/internal/domain"
+	"auth-go/internal/problem"
+	"auth-go/internal/service"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxImportBytes bounds the size of an uploaded import file
+const MaxImportBytes = 64 << 20
+
+// maxImportReportErrors bounds the row errors returned in one response
+const maxImportReportErrors = 1000
+
+type UserImportHandler struct {
+	importService service.UserImportService
+}
+
+func NewUserImportHandler(importService service.UserImportService) *UserImportHandler {
+	return &UserImportHandler{importService}
+}
+
+// Import streams a CSV or JSONL file of users, sent either as the raw body or
+// as the "file" field of a multipart form. Options are query parameters:
+// format (csv or jsonl, detected from the file name or content type when
+// omitted), mode (insert, skip or upsert), dry_run and invite.
+func (h *UserImportHandler) Import(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	invite, _ := strconv.ParseBool(c.Query("invite"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBytes)
+	body, filename, err := importBody(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = detectImportFormat(filename, c.ContentType())
+	}
+
+	report, err := h.importService.Import(c.Request.Context(), body, domain.UserImportOptions{
+		Format:    format,
+		Mode:      c.Query("mode"),
+		DryRun:    dryRun,
+		Invite:    invite,
+		MaxErrors: maxImportReportErrors,
+	})
+	if err != nil {
+		p := problem.FromError(c, err)
+		if p.Code == problem.CodeInternal {
+			handlerLog.ErrorContext(c.Request.Context(), "User import failed", "error", err)
+		}
+		// Rows checked before the failure are still reported
+		if report != nil {
+			p.Extensions = map[string]interface{}{"report": report}
+		}
+		problem.Abort(c, p)
+		return
+	}
+
+	c.JSON(http.StatusOK, DataResponse[*domain.UserImportReport]{report})
+}
+
+// importBody returns the uploaded file without buffering it, along with its
+// name when it came from a multipart form
+func importBody(c *gin.Context) (io.Reader, string, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, "", nil
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", domain.ErrInvalidImport, err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, "", fmt.Errorf("%w: multipart form has no file field", domain.ErrInvalidImport)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: %w", domain.ErrInvalidImport, err)
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+	}
+}
+
+func detectImportFormat(filename string, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return domain.ImportFormatCSV
+	case ".jsonl", ".ndjson":
+		return domain.ImportFormatJSONL
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return domain.ImportFormatCSV
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return domain.ImportFormatJSONL
+	}
+	return ""
+}
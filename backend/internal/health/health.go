@@ -0,0 +1,159 @@
+// Package health runs the dependency checks behind the liveness, readiness
+// and status endpoints.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overall statuses of a Report
+const (
+	StatusReady        = "ready"
+	StatusDegraded     = "degraded"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Statuses of a single check
+const (
+	CheckOK      = "ok"
+	CheckFailing = "failing"
+)
+
+// defaultTimeout bounds checks that set no Timeout
+const defaultTimeout = 3 * time.Second
+
+// Check is a named dependency check
+type Check struct {
+	Name string
+	// Critical checks fail readiness; the others only degrade the status
+	Critical bool
+	Timeout  time.Duration
+	// Run returns details worth showing admins, e.g. pool stats, and an
+	// error when the dependency is unusable
+	Run func(ctx context.Context) (details interface{}, err error)
+}
+
+// Result is the outcome of a Check
+type Result struct {
+	Name       string      `json:"name"`
+	Status     string      `json:"status"`
+	Critical   bool        `json:"critical"`
+	DurationMS float64     `json:"duration_ms"`
+	Error      string      `json:"error,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Status    string    `json:"status"`
+	Ready     bool      `json:"ready"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Checker runs checks and tracks whether the API is shutting down
+type Checker struct {
+	checks   []Check
+	started  time.Time
+	cacheFor time.Duration
+
+	shuttingDown atomic.Bool
+
+	// mu guards last, the report reused for cacheFor so frequent probes
+	// from several sources don't each hit the database
+	mu   sync.Mutex
+	last *Report
+}
+
+// NewChecker returns a checker reusing a report for cacheFor
+func NewChecker(cacheFor time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, started: time.Now(), cacheFor: cacheFor}
+}
+
+// Check runs every check concurrently, or returns the last report when it
+// is recent enough
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.cacheFor {
+		return c.withShutdown(c.last)
+	}
+
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusReady, Ready: true, CheckedAt: time.Now(), Checks: results}
+	for _, result := range results {
+		if result.Status == CheckOK {
+			continue
+		}
+		if result.Critical {
+			report.Status, report.Ready = StatusNotReady, false
+		} else if report.Ready {
+			report.Status = StatusDegraded
+		}
+	}
+	c.last = report
+	return c.withShutdown(report)
+}
+
+// withShutdown marks a copy of report as not ready once shutdown started
+func (c *Checker) withShutdown(report *Report) *Report {
+	if !c.ShuttingDown() {
+		return report
+	}
+	copied := *report
+	copied.Status, copied.Ready = StatusShuttingDown, false
+	return &copied
+}
+
+func run(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := check.Run(ctx)
+	result := Result{
+		Name:       check.Name,
+		Status:     CheckOK,
+		Critical:   check.Critical,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:    details,
+	}
+	if err != nil {
+		result.Status = CheckFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// ShutDown makes readiness fail from now on, so load balancers stop
+// routing new requests while in-flight ones finish
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Uptime returns how long the checker, and so the API, has been running
+func (c *Checker) Uptime() time.Duration {
+	return time.Since(c.started)
+}
@@ -0,0 +1,217 @@
+// Package i18n translates API messages. Catalogs are JSON files named after
+// their language tag, such as en.json and id.json, mapping message keys to
+// text with {placeholders}. English and Indonesian are embedded; a directory
+// of catalogs adds languages or changes shipped messages.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+// Catalog holds the messages of every available language
+type Catalog struct {
+	// languages lists the available languages, the default first
+	languages []language.Tag
+	matcher   language.Matcher
+	messages  map[string]map[string]string
+}
+
+// Load reads the embedded catalogs, then those in dir when set. Messages
+// from dir take precedence. Messages missing from a language fall back to
+// defaultLanguage, which must have a catalog.
+func Load(dir string, defaultLanguage string) (*Catalog, error) {
+	messages := make(map[string]map[string]string)
+	locales, err := fs.Sub(embedded, "locales")
+	if err != nil {
+		return nil, err
+	}
+	if err := readCatalogs(locales, messages); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := readCatalogs(os.DirFS(dir), messages); err != nil {
+			return nil, err
+		}
+	}
+
+	defaultTag, err := language.Parse(defaultLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("default language %q: %w", defaultLanguage, err)
+	}
+	if _, ok := messages[defaultTag.String()]; !ok {
+		return nil, fmt.Errorf("no message catalog for the default language %s", defaultTag)
+	}
+
+	others := make([]string, 0, len(messages))
+	for name := range messages {
+		if name != defaultTag.String() {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	languages := []language.Tag{defaultTag}
+	for _, name := range others {
+		languages = append(languages, language.Make(name))
+	}
+
+	return &Catalog{
+		languages: languages,
+		matcher:   language.NewMatcher(languages),
+		messages:  messages,
+	}, nil
+}
+
+func readCatalogs(fsys fs.FS, into map[string]map[string]string) error {
+	files, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(file, ".json"))
+		if err != nil {
+			return fmt.Errorf("message catalog %s is not named after a language tag: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("message catalog %s: %w", file, err)
+		}
+
+		catalog := into[tag.String()]
+		if catalog == nil {
+			catalog = make(map[string]string, len(messages))
+			into[tag.String()] = catalog
+		}
+		for key, message := range messages {
+			catalog[key] = message
+		}
+	}
+	return nil
+}
+
+// Languages returns the available languages, the default first
+func (c *Catalog) Languages() []string {
+	names := make([]string, len(c.languages))
+	for i, tag := range c.languages {
+		names[i] = tag.String()
+	}
+	return names
+}
+
+// Missing lists, per language, the message keys of the default language it
+// has no translation for
+func (c *Catalog) Missing() map[string][]string {
+	defaults := c.messages[c.languages[0].String()]
+	missing := make(map[string][]string)
+	for _, tag := range c.languages[1:] {
+		for key := range defaults {
+			if _, ok := c.messages[tag.String()][key]; !ok {
+				missing[tag.String()] = append(missing[tag.String()], key)
+			}
+		}
+		sort.Strings(missing[tag.String()])
+	}
+	return missing
+}
+
+// Match picks the available language that best fits an Accept-Language
+// header, "id-ID,id;q=0.9" picks "id". It is the default language when none
+// fits or the header is invalid.
+func (c *Catalog) Match(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return c.languages[0].String()
+	}
+	_, index, _ := c.matcher.Match(tags...)
+	return c.languages[index].String()
+}
+
+// Localizer returns the messages of lang
+func (c *Catalog) Localizer(lang string) *Localizer {
+	return &Localizer{catalog: c, lang: lang}
+}
+
+// Localizer translates messages into one language. A nil Localizer returns
+// the fallback texts, in English.
+type Localizer struct {
+	catalog *Catalog
+	lang    string
+}
+
+// Language returns the language of l, empty for a nil Localizer
+func (l *Localizer) Language() string {
+	if l == nil {
+		return ""
+	}
+	return l.lang
+}
+
+// Text returns the message with key, or fallback when no catalog has it.
+// args are pairs of placeholder names and values: Text("validation.min",
+// "must be at least {param} characters long", "param", "6").
+func (l *Localizer) Text(key string, fallback string, args ...string) string {
+	message := fallback
+	if l != nil {
+		if text, ok := l.catalog.messages[l.lang][key]; ok {
+			message = text
+		} else if text, ok := l.catalog.messages[l.catalog.languages[0].String()][key]; ok {
+			message = text
+		}
+	}
+	if len(args) < 2 {
+		return message
+	}
+
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+args[i]+"}", args[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+// Rule describes a failed validation rule of a binding tag, such as "min"
+// with param "6"
+func (l *Localizer) Rule(tag string, param string) string {
+	return l.Text("validation."+tag, l.Text("validation.invalid", "is invalid"), "param", param)
+}
+
+type contextKey struct{}
+
+// WithLocalizer returns a copy of ctx carrying l
+func WithLocalizer(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// builtin translates outside requests, e.g. in the CLI tools, into English
+var builtin = sync.OnceValue(func() *Localizer {
+	catalog, err := Load("", "en")
+	if err != nil {
+		return nil
+	}
+	return catalog.Localizer("en")
+})
+
+// For returns the Localizer of ctx, or one for English with the embedded
+// catalogs when ctx has none
+func For(ctx context.Context) *Localizer {
+	if l, ok := ctx.Value(contextKey{}).(*Localizer); ok {
+		return l
+	}
+	return builtin()
+}
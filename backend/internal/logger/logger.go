@@ -0,0 +1,200 @@
+// Package logger sets up structured logging with log/slog: JSON or text
+// output, levels per component, request IDs taken from the context and
+// redaction of credentials and email addresses.
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options configure Setup
+type Options struct {
+	// Format is "json" (the default) or "text"
+	Format string
+	// Level is the level of components without their own, e.g. "info"
+	Level string
+	// Levels sets the level of single components, e.g. "database=debug,http=warn"
+	Levels string
+	Output io.Writer
+}
+
+// root is the handler every logger writes through, replaced by Setup
+var root atomic.Pointer[slog.Handler]
+
+// levels are the current minimum levels, replaced by SetLevels
+var levels atomic.Pointer[levelSet]
+
+func init() {
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{ReplaceAttr: redactAttr})
+	root.Store(&h)
+	levels.Store(&levelSet{fallback: slog.LevelInfo})
+}
+
+// Setup installs the output format and levels, and makes slog's default
+// logger, and through it the log package, write through them
+func Setup(opts Options) error {
+	if err := SetLevels(opts.Level, opts.Levels); err != nil {
+		return err
+	}
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	// Filtering happens in componentHandler, so the output takes everything
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug - 4, ReplaceAttr: redactAttr}
+	var h slog.Handler
+	switch opts.Format {
+	case "", "json":
+		h = slog.NewJSONHandler(output, handlerOpts)
+	case "text":
+		h = slog.NewTextHandler(output, handlerOpts)
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	root.Store(&h)
+	slog.SetDefault(For(""))
+	return nil
+}
+
+// For returns the logger of a component, such as "database" or "http". Its
+// lines carry a component attribute and follow the component's level.
+// Loggers may be created before Setup, they pick up its settings.
+func For(component string) *slog.Logger {
+	h := &componentHandler{component: component}
+	if component != "" {
+		h = h.with(step{attrs: []slog.Attr{slog.String("component", component)}})
+	}
+	return slog.New(h)
+}
+
+// SetLevels replaces the default level and the per component levels
+func SetLevels(level string, perComponent string) error {
+	set, err := parseLevels(level, perComponent)
+	if err != nil {
+		return err
+	}
+	levels.Store(set)
+	return nil
+}
+
+// CheckLevels validates levels in the format of SetLevels
+func CheckLevels(level string, perComponent string) error {
+	_, err := parseLevels(level, perComponent)
+	return err
+}
+
+func parseLevels(level string, perComponent string) (*levelSet, error) {
+	set := &levelSet{fallback: slog.LevelInfo, components: make(map[string]slog.Level)}
+	if level != "" {
+		if err := set.fallback.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", level)
+		}
+	}
+	for _, item := range strings.Split(perComponent, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		component, value, ok := strings.Cut(item, "=")
+		var l slog.Level
+		if !ok || strings.TrimSpace(component) == "" || l.UnmarshalText([]byte(strings.TrimSpace(value))) != nil {
+			return nil, fmt.Errorf("log levels must look like database=debug,http=warn, got %q", item)
+		}
+		set.components[strings.TrimSpace(component)] = l
+	}
+	return set, nil
+}
+
+// Enabled reports whether a component logs at level, to skip preparing
+// expensive attributes
+func Enabled(component string, level slog.Level) bool {
+	return level >= levels.Load().of(component)
+}
+
+type levelSet struct {
+	fallback   slog.Level
+	components map[string]slog.Level
+}
+
+func (s *levelSet) of(component string) slog.Level {
+	if l, ok := s.components[component]; ok {
+		return l
+	}
+	return s.fallback
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose log lines carry the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// componentHandler filters by the component's level, adds the request ID
+// of the context and forwards to the root handler. Attributes and groups
+// are applied to the root on every call, so loggers created before Setup
+// write to the output it installs.
+type componentHandler struct {
+	component string
+	steps     []step
+}
+
+// step is a WithAttrs or WithGroup call, replayed in order
+type step struct {
+	attrs []slog.Attr
+	group string
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return Enabled(h.component, level)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	target := *root.Load()
+	for _, s := range h.steps {
+		if s.group != "" {
+			target = target.WithGroup(s.group)
+		} else {
+			target = target.WithAttrs(s.attrs)
+		}
+	}
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	// Link log lines to the trace they were written in
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return target.Handle(ctx, record)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(step{attrs: attrs})
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(step{group: name})
+}
+
+func (h *componentHandler) with(s step) *componentHandler {
+	steps := make([]step, 0, len(h.steps)+1)
+	return &componentHandler{component: h.component, steps: append(append(steps, h.steps...), s)}
+}
@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in logs
+const Redacted = "[REDACTED]"
+
+// secretKeys are substrings of attribute and JSON field names whose values
+// are never logged
+var secretKeys = []string{"password", "token", "secret", "authorization", "cookie", "hash"}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// redactAttr masks secret and email attributes of every log line
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindString {
+		return a
+	}
+	if isSecretKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	if value := a.Value.String(); strings.Contains(value, "@") {
+		return slog.String(a.Key, MaskEmails(value))
+	}
+	return a
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactField returns the loggable form of a named value
+func RedactField(key string, value string) string {
+	if isSecretKey(key) {
+		return Redacted
+	}
+	return MaskEmails(value)
+}
+
+// MaskEmail keeps the first character and the domain of an address, e.g.
+// jane@example.com becomes j***@example.com
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return email
+	}
+	return local[:1] + "***@" + domain
+}
+
+// MaskEmails masks every email address in s
+func MaskEmails(s string) string {
+	return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
+
+// RedactJSON returns a JSON document with secret fields replaced and email
+// addresses masked, or false when data is not JSON
+func RedactJSON(data []byte) ([]byte, bool) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false
+	}
+	redacted, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSecretKey(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return MaskEmails(v)
+	default:
+		return v
+	}
+}
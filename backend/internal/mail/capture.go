@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"sync"
+)
+
+// CaptureTransport keeps the most recent messages in memory so they can be
+// browsed during development. When Inner is set the message is delivered
+// there as well and only captured once delivery succeeded.
+type CaptureTransport struct {
+	Inner Transport
+
+	mu       sync.RWMutex
+	capacity int
+	messages []*Message
+}
+
+func NewCaptureTransport(inner Transport, capacity int) *CaptureTransport {
+	if capacity <= 0 {
+		capacity = 50
+	}
+	return &CaptureTransport{Inner: inner, capacity: capacity}
+}
+
+func (t *CaptureTransport) Send(msg *Message) error {
+	if t.Inner != nil {
+		if err := t.Inner.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = append(t.messages, msg)
+	if len(t.messages) > t.capacity {
+		t.messages = t.messages[len(t.messages)-t.capacity:]
+	}
+	return nil
+}
+
+func (t *CaptureTransport) Close() error {
+	if t.Inner != nil {
+		return t.Inner.Close()
+	}
+	return nil
+}
+
+// List returns the captured messages, newest first
+func (t *CaptureTransport) List() []*Message {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	list := make([]*Message, len(t.messages))
+	for i, msg := range t.messages {
+		list[len(t.messages)-1-i] = msg
+	}
+	return list
+}
+
+// Get returns the captured message with the given ID
+func (t *CaptureTransport) Get(id string) (*Message, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, msg := range t.messages {
+		if msg.ID == id {
+			return msg, true
+		}
+	}
+	return nil, false
+}
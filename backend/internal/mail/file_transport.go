@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FileTransport writes every message as an .eml file into a directory,
+// which is handy for local development and inspection with any mail client.
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if dir == "" {
+		dir = "mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileTransport{dir}, nil
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	name := msg.Date.Format("20060102T150405.000") + "-" + msg.ID + ".eml"
+
+	// Write to a temporary file first so readers never see partial messages
+	tmp, err := os.CreateTemp(t.dir, ".tmp-*.eml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := msg.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(t.dir, name))
+}
+
+func (t *FileTransport) Close() error {
+	return nil
+}
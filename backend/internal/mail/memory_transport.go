@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryTransport keeps sent messages in memory. It is meant for tests,
+// which can use the helpers below to assert on what was sent.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []*Message
+	notify   chan struct{}
+	// Err, when set, is returned by Send to simulate delivery failures
+	Err error
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{notify: make(chan struct{}, 1)}
+}
+
+func (t *MemoryTransport) Send(msg *Message) error {
+	t.mu.Lock()
+	if t.Err != nil {
+		t.mu.Unlock()
+		return t.Err
+	}
+	t.messages = append(t.messages, msg)
+	t.mu.Unlock()
+
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (t *MemoryTransport) Close() error {
+	return nil
+}
+
+// Messages returns a copy of all sent messages in order
+func (t *MemoryTransport) Messages() []*Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Message(nil), t.messages...)
+}
+
+// Len returns the number of sent messages
+func (t *MemoryTransport) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.messages)
+}
+
+// Last returns the most recently sent message or nil
+func (t *MemoryTransport) Last() *Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.messages) == 0 {
+		return nil
+	}
+	return t.messages[len(t.messages)-1]
+}
+
+// SentTo returns the messages addressed to the given recipient
+func (t *MemoryTransport) SentTo(address string) []*Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var found []*Message
+	for _, msg := range t.messages {
+		for _, to := range msg.To {
+			if strings.EqualFold(to, address) {
+				found = append(found, msg)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// WaitFor blocks until at least n messages were sent or the timeout elapses,
+// and reports whether the count was reached. Useful with the async outbox.
+func (t *MemoryTransport) WaitFor(n int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		if t.Len() >= n {
+			return true
+		}
+		select {
+		case <-t.notify:
+		case <-deadline:
+			return t.Len() >= n
+		}
+	}
+}
+
+// Reset forgets all sent messages
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.messages = nil
+}
@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Message is an outgoing multipart/alternative email
+type Message struct {
+	ID      string    `json:"id"`
+	From    string    `json:"from"`
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	HTML    string    `json:"html"`
+	Date    time.Time `json:"date"`
+}
+
+// NewMessage builds a message from a rendered template
+func NewMessage(from string, to string, rendered *Rendered) *Message {
+	return &Message{
+		ID:      newMessageID(),
+		From:    from,
+		To:      []string{to},
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+		Date:    time.Now(),
+	}
+}
+
+// WriteTo writes the message in RFC 5322 format
+func (m *Message) WriteTo(w io.Writer) (int64, error) {
+	return m.toGomail().WriteTo(w)
+}
+
+func (m *Message) toGomail() *gomail.Message {
+	gm := gomail.NewMessage()
+	gm.SetHeader("Message-ID", "<"+m.ID+"@"+hostname()+">")
+	gm.SetHeader("From", m.From)
+	gm.SetHeader("To", m.To...)
+	gm.SetHeader("Subject", m.Subject)
+	gm.SetDateHeader("Date", m.Date)
+	// multipart/alternative: clients pick the last part they can display
+	gm.SetBody("text/plain", m.Text)
+	if m.HTML != "" {
+		gm.AddAlternative("text/html", m.HTML)
+	}
+	return gm
+}
+
+func newMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		return "localhost"
+	}
+	return name
+}
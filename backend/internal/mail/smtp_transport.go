@@ -0,0 +1,175 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+// SMTP TLS modes
+const (
+	// TLSModeStartTLS connects in plain text and upgrades with STARTTLS
+	TLSModeStartTLS = "starttls"
+	// TLSModeImplicit connects over TLS from the start (usually port 465)
+	TLSModeImplicit = "tls"
+)
+
+// SMTPOptions configures the SMTP transport
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLSMode  string
+	// PoolSize is the maximum number of concurrently open connections
+	PoolSize int
+	// IdleTimeout closes pooled connections that were unused for this long
+	IdleTimeout time.Duration
+}
+
+type smtpConn struct {
+	sender   gomail.SendCloser
+	lastUsed time.Time
+}
+
+// SMTPTransport keeps a pool of authenticated connections open instead of
+// dialing the server for every message.
+type SMTPTransport struct {
+	dialer      *gomail.Dialer
+	idleTimeout time.Duration
+
+	slots chan struct{}
+	mu    sync.Mutex
+	idle  []*smtpConn
+}
+
+func NewSMTPTransport(opts SMTPOptions) *SMTPTransport {
+	dialer := gomail.NewDialer(opts.Host, opts.Port, opts.Username, opts.Password)
+	switch opts.TLSMode {
+	case TLSModeImplicit:
+		dialer.SSL = true
+	case TLSModeStartTLS:
+		dialer.SSL = false
+	}
+	dialer.TLSConfig = &tls.Config{ServerName: opts.Host, MinVersion: tls.VersionTLS12}
+
+	poolSize := opts.PoolSize
+	if poolSize <= 0 {
+		poolSize = 2
+	}
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = 30 * time.Second
+	}
+
+	return &SMTPTransport{
+		dialer:      dialer,
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, poolSize),
+	}
+}
+
+func (t *SMTPTransport) Send(msg *Message) error {
+	t.slots <- struct{}{}
+	defer func() { <-t.slots }()
+
+	m := msg.toGomail()
+
+	conn, reused, err := t.acquire()
+	if err != nil {
+		return err
+	}
+
+	err = gomail.Send(conn.sender, m)
+	if err != nil && reused {
+		// The server may have dropped an idle connection; retry once on a fresh one.
+		conn.sender.Close()
+		conn, _, err = t.dial()
+		if err != nil {
+			return err
+		}
+		err = gomail.Send(conn.sender, m)
+	}
+	if err != nil {
+		conn.sender.Close()
+		return err
+	}
+
+	t.release(conn)
+	return nil
+}
+
+// Ping checks that the server accepts connections and greets, without
+// authenticating or sending anything
+func (t *SMTPTransport) Ping(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(t.dialer.Host, strconv.Itoa(t.dialer.Port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if t.dialer.SSL {
+		conn = tls.Client(conn, t.dialer.TLSConfig)
+	}
+
+	client, err := smtp.NewClient(conn, t.dialer.Host)
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (t *SMTPTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var firstErr error
+	for _, conn := range t.idle {
+		if err := conn.sender.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	t.idle = nil
+	return firstErr
+}
+
+func (t *SMTPTransport) acquire() (*smtpConn, bool, error) {
+	t.mu.Lock()
+	for len(t.idle) > 0 {
+		conn := t.idle[len(t.idle)-1]
+		t.idle = t.idle[:len(t.idle)-1]
+		if time.Since(conn.lastUsed) < t.idleTimeout {
+			t.mu.Unlock()
+			return conn, true, nil
+		}
+		conn.sender.Close()
+	}
+	t.mu.Unlock()
+
+	return t.dial()
+}
+
+func (t *SMTPTransport) dial() (*smtpConn, bool, error) {
+	sender, err := t.dialer.Dial()
+	if err != nil {
+		return nil, false, err
+	}
+	return &smtpConn{sender: sender}, false, nil
+}
+
+func (t *SMTPTransport) release(conn *smtpConn) {
+	conn.lastUsed = time.Now()
+
+	t.mu.Lock()
+	t.idle = append(t.idle, conn)
+	t.mu.Unlock()
+}
@@ -0,0 +1,247 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var defaultTemplates embed.FS
+
+// Branding holds the variables shared by every email layout
+type Branding struct {
+	AppName      string
+	AppURL       string
+	LogoURL      string
+	PrimaryColor string
+	SupportEmail string
+}
+
+// Rendered is a fully rendered email with both body parts
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// RendererOptions configures NewRenderer
+type RendererOptions struct {
+	// OverrideDir is an optional directory whose files take precedence over
+	// the embedded defaults, using the same layout (layout.html.tmpl,
+	// <locale>/<name>.html.tmpl, <locale>/<name>.txt.tmpl).
+	OverrideDir   string
+	DefaultLocale string
+	Branding      Branding
+}
+
+// Renderer renders named email templates in the requested locale.
+//
+// Every message consists of <name>.txt.tmpl, which must define a "subject"
+// template, and <name>.html.tmpl. Both are executed inside the shared
+// layout.txt.tmpl and layout.html.tmpl as the "content" template.
+//
+// Templates can be replaced at runtime with Swap.
+type Renderer struct {
+	set atomic.Pointer[templateSet]
+}
+
+// templateSet is what a Renderer renders from
+type templateSet struct {
+	sources       []fs.FS
+	defaultLocale string
+	branding      Branding
+}
+
+type templateData struct {
+	Brand  Branding
+	Locale string
+	Data   interface{}
+}
+
+func NewRenderer(opts RendererOptions) (*Renderer, error) {
+	embedded, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	sources := []fs.FS{embedded}
+	if opts.OverrideDir != "" {
+		info, err := os.Stat(opts.OverrideDir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, errors.New("email template override path is not a directory: " + opts.OverrideDir)
+		}
+		sources = append([]fs.FS{os.DirFS(opts.OverrideDir)}, sources...)
+	}
+
+	defaultLocale := opts.DefaultLocale
+	if defaultLocale == "" {
+		defaultLocale = "en"
+	}
+
+	r := &Renderer{}
+	r.set.Store(&templateSet{
+		sources:       sources,
+		defaultLocale: strings.ToLower(defaultLocale),
+		branding:      opts.Branding,
+	})
+	return r, nil
+}
+
+// Swap makes r render with the templates and options of other
+func (r *Renderer) Swap(other *Renderer) {
+	r.set.Store(other.set.Load())
+}
+
+// Check parses every template so that syntax errors in overrides are found
+// before any email is sent
+func (r *Renderer) Check() error {
+	set := r.set.Load()
+	seen := make(map[string]bool)
+	for _, source := range set.sources {
+		matches, err := fs.Glob(source, "*/*.txt.tmpl")
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			locale, file := path.Split(match)
+			locale = strings.TrimSuffix(locale, "/")
+			name := strings.TrimSuffix(file, ".txt.tmpl")
+			if _, err := set.parseText(locale, name); err != nil {
+				return fmt.Errorf("email template %s: %w", match, err)
+			}
+			if _, err := set.parseHTML(locale, name); err != nil {
+				return fmt.Errorf("email template %s/%s.html.tmpl: %w", locale, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Render renders the named template. The locale falls back from e.g. "id-ID"
+// to "id" and finally to the default locale.
+func (r *Renderer) Render(name string, locale string, data interface{}) (*Rendered, error) {
+	return r.set.Load().render(name, locale, data)
+}
+
+func (r *templateSet) render(name string, locale string, data interface{}) (*Rendered, error) {
+	resolved, err := r.resolveLocale(name, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	td := templateData{Brand: r.branding, Locale: resolved, Data: data}
+
+	textTmpl, err := r.parseText(resolved, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, text bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", td); err != nil {
+		return nil, err
+	}
+	if err := textTmpl.ExecuteTemplate(&text, "layout", td); err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := r.parseHTML(resolved, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := htmlTmpl.ExecuteTemplate(&html, "layout", td); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+func (r *templateSet) resolveLocale(name string, locale string) (string, error) {
+	for _, candidate := range localeCandidates(locale, r.defaultLocale) {
+		if _, err := r.readFile(path.Join(candidate, name+".txt.tmpl")); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("email template not found: " + name)
+}
+
+func (r *templateSet) parseText(locale string, name string) (*texttemplate.Template, error) {
+	layout, err := r.readFile("layout.txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	content, err := r.readFile(path.Join(locale, name+".txt.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := texttemplate.New("layout").Parse(string(layout))
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Parse(string(content))
+}
+
+func (r *templateSet) parseHTML(locale string, name string) (*htmltemplate.Template, error) {
+	layout, err := r.readFile("layout.html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	content, err := r.readFile(path.Join(locale, name+".html.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := htmltemplate.New("layout").Parse(string(layout))
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Parse(string(content))
+}
+
+// readFile returns the first match from the override directory or the embedded defaults
+func (r *templateSet) readFile(name string) ([]byte, error) {
+	var lastErr error
+	for _, source := range r.sources {
+		content, err := fs.ReadFile(source, name)
+		if err == nil {
+			return content, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func localeCandidates(locale string, defaultLocale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+
+	var candidates []string
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if base, _, found := strings.Cut(locale, "-"); found {
+			candidates = append(candidates, base)
+		}
+	}
+	return append(candidates, defaultLocale)
+}
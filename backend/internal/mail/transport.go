@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+)
+
+// Transport names accepted by NewTransport
+const (
+	TransportSMTP   = "smtp"
+	TransportFile   = "file"
+	TransportMemory = "memory"
+)
+
+// Transport delivers rendered messages
+type Transport interface {
+	Send(msg *Message) error
+	Close() error
+}
+
+// TransportOptions configures NewTransport
+type TransportOptions struct {
+	Kind    string
+	SMTP    SMTPOptions
+	FileDir string
+}
+
+// NewTransport returns the transport selected by opts.Kind, defaulting to SMTP
+func NewTransport(opts TransportOptions) (Transport, error) {
+	switch opts.Kind {
+	case "", TransportSMTP:
+		return NewSMTPTransport(opts.SMTP), nil
+	case TransportFile:
+		return NewFileTransport(opts.FileDir)
+	case TransportMemory:
+		return NewMemoryTransport(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", opts.Kind)
+	}
+}
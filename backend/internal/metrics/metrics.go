@@ -0,0 +1,169 @@
+// Package metrics holds the Prometheus collectors of the API and the
+// registry /metrics is served from.
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every collector of the API, along with Go runtime and
+// process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration is labelled with the route template, e.g.
+	// /api/users/:id, so the number of series stays bounded
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Logins counts login attempts by result ("success" or "failure") and,
+	// for failures, reason
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Login attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Registration attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	PasswordResetRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_password_reset_requests_total",
+		Help: "Forgot password requests by result.",
+	}, []string{"result"})
+
+	PasswordResets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_password_resets_total",
+		Help: "Password reset attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	// TokenValidations counts access tokens checked by the auth middleware
+	// by result: "valid", "missing", "malformed", "expired" or "invalid"
+	TokenValidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_validations_total",
+		Help: "Access token validations by result.",
+	}, []string{"result"})
+
+	// TokenRevocations counts password reset tokens invalidated, because a
+	// newer one replaced them or they were used
+	TokenRevocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_revocations_total",
+		Help: "Password reset tokens revoked by reason.",
+	}, []string{"reason"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database statements by operation and status.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "status"})
+
+	EmailSends = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_sends_total",
+		Help: "Emails sent by template and result.",
+	}, []string{"template", "result"})
+
+	EmailSendDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_send_duration_seconds",
+		Help:    "Duration of rendering and sending an email by template.",
+		Buckets: []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"template"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		Logins,
+		Registrations,
+		PasswordResetRequests,
+		PasswordResets,
+		TokenValidations,
+		TokenRevocations,
+		DBQueryDuration,
+		EmailSends,
+		EmailSendDuration,
+	)
+}
+
+// Result labels shared by the counters above
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// RegisterDB adds the connection pool stats of db, such as open, in use and
+// idle connections and the time spent waiting for one
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterOutbox adds the outbox_messages gauge, the number of outbox
+// messages by status. stats counts them on each scrape; statuses are always
+// reported, as 0 when there are none, so alerts on failed messages resolve.
+func RegisterOutbox(stats func(ctx context.Context) (map[string]int64, error), statuses ...string) error {
+	return Registry.Register(&outboxCollector{
+		stats:    stats,
+		statuses: statuses,
+		desc:     prometheus.NewDesc("outbox_messages", "Outbox messages by status.", []string{"status"}, nil),
+	})
+}
+
+type outboxCollector struct {
+	stats    func(ctx context.Context) (map[string]int64, error)
+	statuses []string
+	desc     *prometheus.Desc
+}
+
+func (c *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect leaves the gauge out when the database cannot be queried, rather
+// than failing the whole scrape
+func (c *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	counts, err := c.stats(ctx)
+	if err != nil {
+		return
+	}
+	for _, status := range c.statuses {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), status)
+	}
+}
+
+// Handler serves the registry in the Prometheus exposition format. When
+// token returns a non-empty value, requests must send it as a bearer token.
+func Handler(token func() string) http.Handler {
+	metrics := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := token(); want != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		metrics.ServeHTTP(w, r)
+	})
+}
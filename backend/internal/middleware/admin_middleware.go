@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"auth-go/internal/domain"
+	"auth-go/internal/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware only lets active admins through. It must run after
+// AuthMiddleware. The role is read from the database rather than the token,
+// so demoting or suspending an admin takes effect immediately.
+func AdminMiddleware(userRepo domain.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			abortProblem(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		user, err := userRepo.FindByID(c.Request.Context(), userID.(uint64))
+		if err != nil || user.Role != domain.RoleAdmin || user.Status != domain.StatusActive {
+			abortProblem(c, http.StatusForbidden, problem.CodeForbidden, "Admin access required")
+			return
+		}
+
+		c.Set("userRole", user.Role)
+		c.Next()
+	}
+}
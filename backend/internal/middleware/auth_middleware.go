@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"auth-go/internal/config"
+	"auth-go/internal/metrics"
 	"auth-go/pkg/utils"
 	"net/http"
 	"strings"
@@ -13,21 +14,29 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
+			metrics.TokenValidations.WithLabelValues("missing").Inc()
+			abortProblem(c, http.StatusUnauthorized, "missing_token", "Authorization header missing")
 			return
 		}
 
 		tokenString := strings.Split(authHeader, "Bearer ")
 		if len(tokenString) < 2 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+			metrics.TokenValidations.WithLabelValues("malformed").Inc()
+			abortProblem(c, http.StatusUnauthorized, "malformed_token", "Invalid token format")
 			return
 		}
 
 		claims, err := utils.ValidateToken(tokenString[1], cfg.JWTSecret)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			result := "invalid"
+			if utils.IsTokenExpired(err) {
+				result = "expired"
+			}
+			metrics.TokenValidations.WithLabelValues(result).Inc()
+			abortProblem(c, http.StatusUnauthorized, "invalid_token", "Invalid or expired token")
 			return
 		}
+		metrics.TokenValidations.WithLabelValues("valid").Inc()
 
 		c.Set("userID", claims.UserID)
 		c.Set("userEmail", claims.Email)
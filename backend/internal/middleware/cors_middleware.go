@@ -3,16 +3,35 @@ package middleware
 import (
 	"time"
 
+	"auth-go/pkg/utils"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func CORSMiddleware() gin.HandlerFunc {
+// CORSPolicy lists the origins allowed to call a group of routes
+type CORSPolicy struct {
+	AllowedOrigins []utils.OriginPattern
+}
+
+// Allows reports whether origin matches any allowed origin
+func (p CORSPolicy) Allows(origin string) bool {
+	for _, pattern := range p.AllowedOrigins {
+		if pattern.Match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func CORSMiddleware(policies *Policies[CORSPolicy]) gin.HandlerFunc {
 	return cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://127.0.0.1:5173"},
+		AllowOriginWithContextFunc: func(c *gin.Context, origin string) bool {
+			return policies.For(c.Request.URL.Path).Allows(origin)
+		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", RequestIDHeader, "traceparent", "tracestate", "baggage"},
+		ExposeHeaders:    []string{"Content-Length", RequestIDHeader, "traceresponse"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"auth-go/internal/config"
+	"auth-go/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureMiddleware answers 404 while the named feature is turned off in
+// FEATURE_FLAGS
+func FeatureMiddleware(store *config.Store, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !store.Current().Feature(feature) {
+			abortProblem(c, http.StatusNotFound, problem.CodeFeatureDisabled, "This feature is disabled")
+			return
+		}
+		c.Next()
+	}
+}
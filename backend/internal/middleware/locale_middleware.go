@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"auth-go/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleMiddleware picks the response language from Accept-Language and adds
+// a localizer for it to the request context. catalog is called on every
+// request so the catalogs can be reloaded.
+func LocaleMiddleware(catalog func() *i18n.Catalog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current := catalog()
+		lang := current.Match(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", lang)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Request = c.Request.WithContext(i18n.WithLocalizer(c.Request.Context(), current.Localizer(lang)))
+		c.Next()
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"auth-go/internal/logger"
+	"auth-go/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLoggedBody is how much of a request body is kept for the log
+const maxLoggedBody = 4 << 10
+
+var httpLog = logger.For("http")
+
+// LoggerMiddleware logs one line per request. Bodies of JSON requests are
+// included, redacted, for failed requests or when the http component logs
+// at debug level. Successful requests to quietPaths, such as health probes,
+// are logged at debug level.
+func LoggerMiddleware(quietPaths ...string) gin.HandlerFunc {
+	quiet := make(map[string]bool, len(quietPaths))
+	for _, path := range quietPaths {
+		quiet[path] = true
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var body *cappedBuffer
+		if c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+			body = &cappedBuffer{max: maxLoggedBody}
+			c.Request.Body = readCloser{io.TeeReader(c.Request.Body, body), c.Request.Body}
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		case quiet[c.Request.URL.Path]:
+			level = slog.LevelDebug
+		}
+		ctx := c.Request.Context()
+		if !httpLog.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if c.Request.URL.RawQuery != "" {
+			attrs = append(attrs, slog.String("query", redactQuery(c.Request.URL.Query())))
+		}
+		if userID, ok := c.Get("userID"); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		if body != nil && body.Len() > 0 && (status >= 400 || httpLog.Enabled(ctx, slog.LevelDebug)) {
+			if redacted, ok := logger.RedactJSON(body.Bytes()); ok {
+				attrs = append(attrs, slog.String("body", string(redacted)))
+			} else if body.truncated {
+				attrs = append(attrs, slog.String("body", "[truncated]"))
+			}
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		httpLog.LogAttrs(ctx, level, "HTTP request", attrs...)
+	}
+}
+
+// RecoveryMiddleware turns panics into logged 500 responses
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		httpLog.ErrorContext(c.Request.Context(), "Panic while handling request",
+			"error", err, "path", c.Request.URL.Path, "stack", string(debug.Stack()))
+		abortProblem(c, http.StatusInternalServerError, problem.CodeInternal, "Internal server error, quote the request ID when reporting it")
+	})
+}
+
+// redactQuery formats query parameters with secrets and emails masked
+func redactQuery(query url.Values) string {
+	parts := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			parts = append(parts, key+"="+logger.RedactField(key, value))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+// cappedBuffer keeps the first max bytes written to it
+type cappedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		b.Buffer.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// readCloser reads from a tee of the body and closes the original
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"auth-go/internal/domain"
+	"auth-go/internal/i18n"
+	"auth-go/internal/logger"
+	"auth-go/internal/openapi"
+	"auth-go/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// codeResponseInvalid answers responses replaced for breaking the document
+const codeResponseInvalid = "response_invalid"
+
+var openAPILog = logger.For("openapi")
+
+// OpenAPIValidationMiddleware rejects requests that break the OpenAPI
+// document while enabled returns true: unknown JSON fields, values of the
+// wrong type or outside their enum, missing required fields. They get the
+// same validation_failed problem as handler validation errors.
+//
+// In gin's test mode validation is always on and responses are checked too.
+// A JSON response that breaks the document is replaced by a 500 problem
+// naming the mismatch, so drift between handlers and the document fails
+// tests instead of clients.
+func OpenAPIValidationMiddleware(validator *openapi.Validator, enabled func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		testMode := gin.Mode() == gin.TestMode
+		if !testMode && !enabled() {
+			c.Next()
+			return
+		}
+		route := validator.Route(c.Request.Method, c.FullPath())
+		if route == nil {
+			c.Next()
+			return
+		}
+
+		issues := route.ValidateQuery(c.Request.URL.Query())
+		if route.ChecksBody() && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				problem.Abort(c, problem.FromError(c, err))
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			// Bodies that are not JSON are reported by the handler
+			if bodyIssues, err := route.ValidateBody(body); err == nil {
+				issues = append(issues, bodyIssues...)
+			}
+		}
+		if len(issues) > 0 {
+			loc := i18n.For(c.Request.Context())
+			fields := make([]domain.FieldError, 0, len(issues))
+			for _, issue := range issues {
+				fields = append(fields, domain.FieldError{
+					Field:   issue.Field,
+					Code:    issue.Code,
+					Param:   issue.Param,
+					Message: loc.Rule(issue.Code, issue.Param),
+				})
+			}
+			problem.Abort(c, problem.FromError(c, domain.NewValidationError(fields...)))
+			return
+		}
+
+		if !testMode {
+			c.Next()
+			return
+		}
+		writer := &contractWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		err := route.ValidateResponse(writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+		switch {
+		case err == nil && writer.buffering:
+			_, _ = writer.ResponseWriter.Write(writer.body.Bytes())
+		case err == nil:
+		case writer.buffering:
+			openAPILog.ErrorContext(c.Request.Context(), "Response breaks the OpenAPI document", "route", c.FullPath(), "error", err)
+			problem.Abort(c, problem.New(c, http.StatusInternalServerError, codeResponseInvalid, "Response does not match the OpenAPI document: "+err.Error()))
+		default:
+			// Streamed responses have been sent already, only the log can tell
+			openAPILog.ErrorContext(c.Request.Context(), "Response breaks the OpenAPI document", "route", c.FullPath(), "error", err)
+		}
+	}
+}
+
+// contractWriter holds back JSON responses until they are checked. Other
+// responses, such as file downloads, are streamed as usual.
+type contractWriter struct {
+	gin.ResponseWriter
+	decided   bool
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (w *contractWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+		w.buffering = mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	}
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *contractWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *contractWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *contractWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Flush is deferred for held back responses, which could not be replaced
+// once sent
+func (w *contractWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *contractWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
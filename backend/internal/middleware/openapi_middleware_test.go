@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"auth-go/internal/domain"
+	"auth-go/internal/openapi"
+	"auth-go/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type noteInput struct {
+	Title    string `json:"title" binding:"required"`
+	Priority int    `json:"priority" binding:"omitempty,min=1"`
+}
+
+type noteResponse struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// newContractRouter serves a documented route through the validation
+// middleware, answering with response. The feature flag is off: test mode
+// alone turns validation on.
+func newContractRouter(t *testing.T, response interface{}) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	doc := openapi.New(openapi.Info{Title: "Test", Version: "1"}, problem.Details{})
+	doc.Add(openapi.Route{
+		Method:    http.MethodPost,
+		Path:      "/notes",
+		Body:      noteInput{},
+		Responses: map[int]openapi.Body{http.StatusCreated: {Value: noteResponse{}}},
+		Errors:    []int{http.StatusBadRequest},
+	})
+	validator, err := openapi.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.Use(OpenAPIValidationMiddleware(validator, func() bool { return false }))
+	r.POST("/notes", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, response)
+	})
+	return r
+}
+
+func postNote(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/notes", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) problem.Details {
+	t.Helper()
+	var p problem.Details
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	return p
+}
+
+func TestOpenAPIValidationRejectsRequests(t *testing.T) {
+	r := newContractRouter(t, noteResponse{ID: 1, Title: "Groceries"})
+
+	tests := []struct {
+		name string
+		body string
+		want domain.FieldError
+	}{
+		{"unknown field", `{"title":"Groceries","colour":"red"}`, domain.FieldError{Field: "colour", Code: "unknown_field"}},
+		{"wrong type", `{"title":"Groceries","priority":"high"}`, domain.FieldError{Field: "priority", Code: "type", Param: "integer"}},
+		{"missing field", `{"priority":1}`, domain.FieldError{Field: "title", Code: "required"}},
+	}
+	for _, tt := range tests {
+		w := postNote(r, tt.body)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400: %s", tt.name, w.Code, w.Body)
+			continue
+		}
+		p := decodeProblem(t, w)
+		if p.Code != "validation_failed" || len(p.Errors) != 1 {
+			t.Errorf("%s: problem %s with errors %+v, want one validation_failed error", tt.name, p.Code, p.Errors)
+			continue
+		}
+		got := p.Errors[0]
+		if got.Field != tt.want.Field || got.Code != tt.want.Code || got.Param != tt.want.Param {
+			t.Errorf("%s: error %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if w := postNote(r, `{"title":"Groceries","priority":1}`); w.Code != http.StatusCreated {
+		t.Fatalf("valid request: status %d, want 201: %s", w.Code, w.Body)
+	}
+}
+
+func TestOpenAPIValidationCatchesResponseDrift(t *testing.T) {
+	// The handler answers with a field the document does not know about
+	drifted := map[string]interface{}{"id": 1, "title": "Groceries", "owner": "ana"}
+	r := newContractRouter(t, drifted)
+
+	w := postNote(r, `{"title":"Groceries"}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500: %s", w.Code, w.Body)
+	}
+	if p := decodeProblem(t, w); p.Code != codeResponseInvalid || !strings.Contains(p.Detail, "owner") {
+		t.Fatalf("problem %s (%s), want %s naming the field", p.Code, p.Detail, codeResponseInvalid)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// Policies holds a default policy and overrides for route groups, keyed by
+// path prefix. They are chosen by path rather than attached to the groups
+// because CORS preflights for unknown methods never reach group middleware.
+// Set replaces them at runtime, e.g. on a config reload.
+type Policies[T any] struct {
+	current atomic.Pointer[policySet[T]]
+}
+
+type policySet[T any] struct {
+	fallback T
+	groups   map[string]T
+}
+
+func NewPolicies[T any](fallback T, groups map[string]T) *Policies[T] {
+	p := &Policies[T]{}
+	p.Set(fallback, groups)
+	return p
+}
+
+func (p *Policies[T]) Set(fallback T, groups map[string]T) {
+	p.current.Store(&policySet[T]{fallback: fallback, groups: groups})
+}
+
+// For returns the policy of the longest group prefix containing path
+func (p *Policies[T]) For(path string) T {
+	set := p.current.Load()
+	policy, longest := set.fallback, -1
+	for prefix, groupPolicy := range set.groups {
+		if len(prefix) > longest && (path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")) {
+			policy, longest = groupPolicy, len(prefix)
+		}
+	}
+	return policy
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"auth-go/internal/problem"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is a sustained rate in requests per second with a burst size.
+// A zero RPS disables limiting.
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+// clientIdleTime is how long a client's bucket is kept after its last request
+const clientIdleTime = 10 * time.Minute
+
+// RateLimitMiddleware limits requests per client IP with token buckets.
+// limit is called on every request so the limit can change at runtime;
+// a change resets every bucket.
+func RateLimitMiddleware(limit func() RateLimit) gin.HandlerFunc {
+	l := &rateLimiter{clients: make(map[string]*rateClient)}
+	return func(c *gin.Context) {
+		current := limit()
+		if current.RPS <= 0 {
+			c.Next()
+			return
+		}
+
+		limiter := l.limiter(c.ClientIP(), current)
+		if !limiter.Allow() {
+			retryAfter := math.Ceil(1 / current.RPS)
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter)))
+			abortProblem(c, http.StatusTooManyRequests, problem.CodeRateLimited, "Too many requests, please try again later")
+			return
+		}
+		c.Next()
+	}
+}
+
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     RateLimit
+	clients   map[string]*rateClient
+	lastSweep time.Time
+}
+
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func (l *rateLimiter) limiter(ip string, limit RateLimit) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if limit != l.limit {
+		l.limit = limit
+		l.clients = make(map[string]*rateClient)
+	}
+	if now.Sub(l.lastSweep) > time.Minute {
+		l.lastSweep = now
+		for key, client := range l.clients {
+			if now.Sub(client.lastSeen) > clientIdleTime {
+				delete(l.clients, key)
+			}
+		}
+	}
+
+	client, ok := l.clients[ip]
+	if !ok {
+		client = &rateClient{limiter: rate.NewLimiter(rate.Limit(limit.RPS), limit.Burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = now
+	return client.limiter
+}
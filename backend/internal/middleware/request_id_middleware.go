@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"regexp"
+
+	"auth-go/internal/logger"
+	"auth-go/internal/problem"
+	"auth-go/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID keeps IDs from clients and proxies to a safe, short form
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// RequestIDMiddleware takes the request ID from X-Request-ID, so a request
+// can be followed through a gateway, or generates one. It is echoed in the
+// response and added to the request context for logging.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id, _ = utils.RandomToken(16)
+		}
+		c.Header(RequestIDHeader, id)
+		c.Set("requestID", id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// abortProblem answers with problem details and stops the handler chain.
+// They carry the request ID, which lets users quote a failure to support.
+func abortProblem(c *gin.Context, status int, code string, detail string) {
+	problem.Abort(c, problem.New(c, status, code, detail))
+}
@@ -1,14 +1,121 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
 
-func SecurityHeadersMiddleware() gin.HandlerFunc {
+	"auth-go/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityPolicy is the set of security headers sent on a group of routes
+type SecurityPolicy struct {
+	CSP CSP
+	// PermissionsPolicy, CrossOriginOpenerPolicy and CrossOriginEmbedderPolicy
+	// are sent as is when set
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginEmbedderPolicy string
+	HSTS                      HSTS
+	// TrustedProxies may tell a TLS request apart with X-Forwarded-Proto
+	TrustedProxies []*net.IPNet
+}
+
+// HSTS is the Strict-Transport-Security policy. A zero MaxAge disables it.
+type HSTS struct {
+	MaxAge            time.Duration
+	IncludeSubdomains bool
+	Preload           bool
+}
+
+func (h HSTS) String() string {
+	value := fmt.Sprintf("max-age=%d", int64(h.MaxAge.Seconds()))
+	if h.IncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if h.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+func SecurityHeadersMiddleware(policies *Policies[SecurityPolicy]) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		policy := policies.For(c.Request.URL.Path)
+
 		c.Header("X-Content-Type-Options", "nosniff")
 		c.Header("X-Frame-Options", "DENY")
 		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		c.Header("Content-Security-Policy", "default-src 'self'")
+
+		if len(policy.CSP.Directives) > 0 {
+			var nonce string
+			if policy.CSP.Nonce {
+				var err error
+				if nonce, err = utils.RandomToken(16); err == nil {
+					c.Set(cspNonceKey, nonce)
+				}
+			}
+			c.Header(policy.CSP.Header(nonce))
+			if policy.CSP.ReportURI != "" {
+				c.Header("Reporting-Endpoints", fmt.Sprintf("%s=%q", cspReportGroup, policy.CSP.ReportURI))
+			}
+		}
+		if policy.PermissionsPolicy != "" {
+			c.Header("Permissions-Policy", policy.PermissionsPolicy)
+		}
+		if policy.CrossOriginOpenerPolicy != "" {
+			c.Header("Cross-Origin-Opener-Policy", policy.CrossOriginOpenerPolicy)
+		}
+		if policy.CrossOriginEmbedderPolicy != "" {
+			c.Header("Cross-Origin-Embedder-Policy", policy.CrossOriginEmbedderPolicy)
+		}
+		// Browsers ignore HSTS over plain HTTP, and sending it there would
+		// wrongly suggest the deployment is served over TLS
+		if policy.HSTS.MaxAge > 0 && isTLS(c, policy.TrustedProxies) {
+			c.Header("Strict-Transport-Security", policy.HSTS.String())
+		}
 		c.Next()
 	}
 }
+
+// isTLS reports whether the client connected over TLS, either to us or to
+// a trusted proxy in front of us
+func isTLS(c *gin.Context, trustedProxies []*net.IPNet) bool {
+	if c.Request.TLS != nil {
+		return true
+	}
+	proto, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Proto"), ",")
+	if !strings.EqualFold(strings.TrimSpace(proto), "https") {
+		return false
+	}
+	remote := net.ParseIP(c.RemoteIP())
+	for _, network := range trustedProxies {
+		if remote != nil && network.Contains(remote) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNetworks parses IP addresses and CIDRs, as used for trusted proxies
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			if ip := net.ParseIP(value); ip != nil && ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR %q", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
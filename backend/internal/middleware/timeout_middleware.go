@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutMiddleware puts a deadline on the request context so database
+// queries and other work started by the handler are cancelled once it passes.
+func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// ServerTimeoutMiddleware extends the server's read and write deadlines for
+// routes that legitimately take longer, such as bulk imports and exports
+func ServerTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout > 0 {
+			deadline := time.Now().Add(timeout)
+			controller := http.NewResponseController(c.Writer)
+			// Errors mean the connection does not support deadlines, e.g. in
+			// tests with a recorder, where the server timeouts do not apply either
+			_ = controller.SetReadDeadline(deadline)
+			_ = controller.SetWriteDeadline(deadline)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,342 @@
+// Package openapi builds the OpenAPI 3.1 document of the API. Operations are
+// described next to the routes, while request and response schemas are
+// derived by reflection from the handler input and response types, so the
+// document follows the code.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of the documents built here
+const Version = "3.1.0"
+
+// JSONContentType and ProblemContentType are the media types of JSON
+// responses and of problem details
+const (
+	JSONContentType    = "application/json"
+	ProblemContentType = "application/problem+json"
+)
+
+// Document is an OpenAPI document. Build it with New and Add.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// schemaTypes maps component names to their Go types
+	schemaTypes map[string]reflect.Type
+	// names are the component names chosen with Define
+	names map[reflect.Type]string
+	// routes maps "METHOD /gin/path" to the documented operation
+	routes  map[string]*Operation
+	problem *Schema
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// PathItem maps lower case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Route describes the operation of a route registered on the router
+type Route struct {
+	// Method and Path are as registered with gin, e.g. "/jobs/:id"
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	// Security names the security scheme required, if any. Routes that
+	// require one document 401 responses.
+	Security string
+
+	// Params describes query and path parameters. Path parameters not
+	// listed are documented as strings.
+	Params []Param
+	// Body is a value of the JSON request body type, e.g. LoginInput{}
+	Body interface{}
+	// BodyTypes lists the media types of non JSON request bodies
+	BodyTypes []string
+
+	// Responses maps status codes to successful responses
+	Responses map[int]Body
+	// Errors lists the status codes answered with problem details, besides
+	// the default internal error
+	Errors []int
+}
+
+// Param is a query or path parameter
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      *Schema
+}
+
+// Query and Path build parameters
+func Query(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "query", Schema: schema, Description: description}
+}
+
+func Path(name string, schema *Schema, description string) Param {
+	return Param{Name: name, In: "path", Required: true, Schema: schema, Description: description}
+}
+
+// Body is a response. Value is a value of the JSON body type; Types lists
+// other media types, e.g. of downloads. Responses with neither are empty.
+type Body struct {
+	Description string
+	Value       interface{}
+	Types       []string
+	Headers     map[string]string
+}
+
+// Schema constructors for parameters
+func String() *Schema  { return &Schema{Type: "string"} }
+func Integer() *Schema { return &Schema{Type: "integer"} }
+func Boolean() *Schema { return &Schema{Type: "boolean"} }
+
+func Enum(values ...string) *Schema {
+	schema := &Schema{Type: "string"}
+	for _, value := range values {
+		schema.Enum = append(schema.Enum, value)
+	}
+	return schema
+}
+
+func DateTime() *Schema { return &Schema{Type: "string", Format: "date-time"} }
+
+// New returns an empty document. problemType is the type of problem details,
+// the body of every error response.
+func New(info Info, problemType interface{}) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas:         map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{},
+		},
+		schemaTypes: map[string]reflect.Type{},
+		names:       map[reflect.Type]string{},
+		routes:      map[string]*Operation{},
+	}
+	d.Define("Problem", problemType)
+	d.problem = d.SchemaOf(problemType)
+	// Problems may carry extension members, such as an import report
+	d.Components.Schemas["Problem"].AdditionalProperties = nil
+	return d
+}
+
+// Define names the component schema of v's type, instead of its Go name.
+// Call it before the type is first used.
+func (d *Document) Define(name string, v interface{}) {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	d.names[t] = name
+}
+
+// SchemaOf returns the schema of v's type, adding named structs to the
+// components
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+var ginParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+// Add documents a route. Documenting the same route twice is a programming
+// error and panics.
+func (d *Document) Add(route Route) {
+	key := routeKey(route.Method, route.Path)
+	if _, ok := d.routes[key]; ok {
+		panic("openapi: route documented twice: " + key)
+	}
+
+	op := &Operation{
+		OperationID: operationID(route.Method, route.Path),
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Responses:   map[string]*Response{},
+	}
+
+	described := map[string]Param{}
+	for _, param := range route.Params {
+		described[param.In+" "+param.Name] = param
+	}
+	for _, match := range ginParam.FindAllStringSubmatch(route.Path, -1) {
+		param, ok := described["path "+match[1]]
+		if !ok {
+			param = Path(match[1], String(), "")
+		}
+		op.Parameters = append(op.Parameters, param.parameter())
+	}
+	for _, param := range route.Params {
+		if param.In != "path" {
+			op.Parameters = append(op.Parameters, param.parameter())
+		}
+	}
+
+	if route.Body != nil || len(route.BodyTypes) > 0 {
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{}}
+		if route.Body != nil {
+			op.RequestBody.Content[JSONContentType] = &MediaType{Schema: d.SchemaOf(route.Body)}
+		}
+		for _, contentType := range route.BodyTypes {
+			op.RequestBody.Content[contentType] = &MediaType{}
+		}
+	}
+
+	for status, body := range route.Responses {
+		op.Responses[strconv.Itoa(status)] = d.response(status, body)
+	}
+	errors := route.Errors
+	if route.Security != "" {
+		op.Security = []map[string][]string{{route.Security: {}}}
+		errors = append([]int{http.StatusUnauthorized}, errors...)
+	}
+	for _, status := range errors {
+		op.Responses[strconv.Itoa(status)] = d.problemResponse(http.StatusText(status))
+	}
+	op.Responses["default"] = d.problemResponse("Unexpected error")
+
+	path := ginParam.ReplaceAllString(route.Path, "{$1}")
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+	(*item)[strings.ToLower(route.Method)] = op
+	d.routes[key] = op
+}
+
+// Operation returns the operation documented for a route, given its gin
+// method and path, or nil
+func (d *Document) Operation(method string, path string) *Operation {
+	return d.routes[routeKey(method, path)]
+}
+
+// Documented reports whether a route, given its gin method and path, has
+// been documented
+func (d *Document) Documented(method string, path string) bool {
+	return d.routes[routeKey(method, path)] != nil
+}
+
+func (d *Document) response(status int, body Body) *Response {
+	description := body.Description
+	if description == "" {
+		description = http.StatusText(status)
+	}
+	response := &Response{Description: description}
+	if body.Value != nil || len(body.Types) > 0 {
+		response.Content = map[string]*MediaType{}
+	}
+	if body.Value != nil {
+		response.Content[JSONContentType] = &MediaType{Schema: d.SchemaOf(body.Value)}
+	}
+	for _, contentType := range body.Types {
+		response.Content[contentType] = &MediaType{}
+	}
+	for name, description := range body.Headers {
+		if response.Headers == nil {
+			response.Headers = map[string]*Header{}
+		}
+		response.Headers[name] = &Header{Description: description, Schema: String()}
+	}
+	return response
+}
+
+func (d *Document) problemResponse(description string) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{ProblemContentType: {Schema: d.problem}},
+	}
+}
+
+func (p Param) parameter() *Parameter {
+	schema := p.Schema
+	if schema == nil {
+		schema = String()
+	}
+	return &Parameter{Name: p.Name, In: p.In, Description: p.Description, Required: p.Required || p.In == "path", Schema: schema}
+}
+
+func routeKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// operationID derives an ID such as "getApiUsersExportJobsId"
+func operationID(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12), as used by OpenAPI 3.1
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        interface{}        `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties is a *Schema, or false to reject unknown fields
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema   `json:"anyOf,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaOf returns the schema of t. Named structs are added to the
+// components and referenced; generic ones, such as response envelopes, are
+// inlined.
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return d.structSchema(t)
+		}
+		name, ok := d.names[t]
+		if !ok {
+			name = d.schemaName(t)
+		}
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Registered before the fields, so recursive types terminate
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// interface{} and anything else accept any value
+	return &Schema{}
+}
+
+// schemaName names t in the components, qualified by its package when
+// another package has a type of the same name
+func (d *Document) schemaName(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := d.schemaTypes[name]; ok && other != t {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	d.schemaTypes[name] = t
+	return name
+}
+
+// structSchema describes the JSON fields of t. Unknown fields are rejected.
+//
+// A field is required when its binding tag says so; fields without binding
+// rules, which are those of responses, are required unless omitempty.
+// Binding rules become constraints: min and max, email, oneof.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	d.addFields(schema, t)
+	return schema
+}
+
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(schema, embedded)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := strings.Contains(options, "omitempty")
+
+		property := d.schemaOf(field.Type)
+		binding, hasBinding := field.Tag.Lookup("binding")
+		if hasBinding {
+			property = applyBinding(property, binding)
+		}
+		// Pointers that are not omitted are written as null
+		if field.Type.Kind() == reflect.Pointer && !omitEmpty {
+			property = nullable(property)
+		}
+		schema.Properties[name] = property
+
+		required := !omitEmpty
+		if hasBinding {
+			required = hasRule(binding, "required")
+		}
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyBinding adds the constraints of go-playground/validator rules
+func applyBinding(schema *Schema, binding string) *Schema {
+	if schema.Ref != "" {
+		return schema
+	}
+	constrained := *schema
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "email":
+			constrained.Format = "email"
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			value := float64(n)
+			switch {
+			case constrained.Type == "string" && name == "min":
+				constrained.MinLength = &n
+			case constrained.Type == "string":
+				constrained.MaxLength = &n
+			case name == "min":
+				constrained.Minimum = &value
+			default:
+				constrained.Maximum = &value
+			}
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				constrained.Enum = append(constrained.Enum, value)
+			}
+		case "eqfield":
+			constrained.Description = "Must match " + snakeCase(param) + "."
+		case "bcp47_language_tag":
+			constrained.Description = "A BCP 47 language tag, such as en or id-ID."
+		}
+	}
+	return &constrained
+}
+
+func hasRule(binding string, rule string) bool {
+	for _, r := range strings.Split(binding, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable also allows null
+func nullable(schema *Schema) *Schema {
+	if typ, ok := schema.Type.(string); ok && schema.Ref == "" {
+		copied := *schema
+		copied.Type = []string{typ, "null"}
+		return &copied
+	}
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
+
+// snakeCase turns a Go field name into its JSON name
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,310 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+// documentURL locates the document among the compiled schemas
+const documentURL = "urn:openapi"
+
+// Validator checks requests and responses against the schemas of a
+// document. Create it with NewValidator once every route is added.
+type Validator struct {
+	routes map[string]*RouteValidator
+}
+
+// RouteValidator checks the requests and responses of one route
+type RouteValidator struct {
+	// body is the JSON request body schema, nil when the route takes none or
+	// accepts other media types too
+	body  *jsonschema.Schema
+	query map[string]*queryParam
+	// responses maps status codes, or "default", to media types and their
+	// schema, nil for bodies that are not checked
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+type queryParam struct {
+	typ      string
+	required bool
+	schema   *jsonschema.Schema
+}
+
+// Issue is a value breaking the document. Field is the dotted path of the
+// value, Code the failed rule as in validation errors (required, type,
+// unknown_field, min, max, oneof...) and Param its argument.
+type Issue struct {
+	Field string
+	Code  string
+	Param string
+}
+
+func NewValidator(doc *Document) (*Validator, error) {
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	if err := compiler.AddResource(documentURL, resource); err != nil {
+		return nil, err
+	}
+
+	v := &Validator{routes: map[string]*RouteValidator{}}
+	for key, op := range doc.routes {
+		method, path, _ := strings.Cut(key, " ")
+		pointer := "#/paths/" + escapePointer(ginParam.ReplaceAllString(path, "{$1}")) + "/" + strings.ToLower(method)
+		compile := func(location string) (*jsonschema.Schema, error) {
+			schema, err := compiler.Compile(documentURL + pointer + location)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			return schema, nil
+		}
+
+		route := &RouteValidator{query: map[string]*queryParam{}, responses: map[string]map[string]*jsonschema.Schema{}}
+		if op.RequestBody != nil && len(op.RequestBody.Content) == 1 && op.RequestBody.Content[JSONContentType] != nil {
+			if route.body, err = compile("/requestBody/content/" + escapePointer(JSONContentType) + "/schema"); err != nil {
+				return nil, err
+			}
+		}
+		for i, param := range op.Parameters {
+			if param.In != "query" {
+				continue
+			}
+			schema, err := compile(fmt.Sprintf("/parameters/%d/schema", i))
+			if err != nil {
+				return nil, err
+			}
+			typ, _ := param.Schema.Type.(string)
+			route.query[param.Name] = &queryParam{typ: typ, required: param.Required, schema: schema}
+		}
+		for status, response := range op.Responses {
+			route.responses[status] = map[string]*jsonschema.Schema{}
+			for contentType, media := range response.Content {
+				var schema *jsonschema.Schema
+				if media.Schema != nil {
+					location := "/responses/" + status + "/content/" + escapePointer(contentType) + "/schema"
+					if schema, err = compile(location); err != nil {
+						return nil, err
+					}
+				}
+				route.responses[status][contentType] = schema
+			}
+		}
+		v.routes[key] = route
+	}
+	return v, nil
+}
+
+// Route returns the validator of a route, given its gin method and path, or
+// nil for undocumented routes
+func (v *Validator) Route(method string, path string) *RouteValidator {
+	return v.routes[routeKey(method, path)]
+}
+
+// ChecksBody reports whether the route takes a JSON body that ValidateBody
+// checks
+func (r *RouteValidator) ChecksBody() bool {
+	return r.body != nil
+}
+
+// ValidateQuery checks the documented query parameters. Values are converted
+// to the parameter type first; empty values count as absent. Undocumented
+// parameters are ignored, as proxies and caches add their own.
+func (r *RouteValidator) ValidateQuery(query url.Values) []Issue {
+	names := make([]string, 0, len(r.query))
+	for name := range r.query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []Issue
+	for _, name := range names {
+		param := r.query[name]
+		raw := query.Get(name)
+		if raw == "" {
+			if param.required {
+				issues = append(issues, Issue{Field: name, Code: "required"})
+			}
+			continue
+		}
+
+		var value interface{} = raw
+		switch param.typ {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(raw, 64); err != nil {
+				issues = append(issues, Issue{Field: name, Code: "type", Param: param.typ})
+				continue
+			}
+			value = json.Number(raw)
+		case "boolean":
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				issues = append(issues, Issue{Field: name, Code: "type", Param: param.typ})
+				continue
+			}
+			value = b
+		}
+		if err := param.schema.Validate(value); err != nil {
+			issues = append(issues, issuesOf(err, name)...)
+		}
+	}
+	return issues
+}
+
+// ValidateBody checks a JSON request body. Bodies that are not JSON return
+// an error, leaving the handler to report them as it always has.
+func (r *RouteValidator) ValidateBody(body []byte) ([]Issue, error) {
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.body.Validate(value); err != nil {
+		return issuesOf(err, ""), nil
+	}
+	return nil, nil
+}
+
+// ValidateResponse checks that the status and content type of a response are
+// documented, and its body matches their schema
+func (r *RouteValidator) ValidateResponse(status int, contentType string, body []byte) error {
+	content, ok := r.responses[strconv.Itoa(status)]
+	if !ok {
+		if content, ok = r.responses["default"]; !ok {
+			return fmt.Errorf("status %d is not documented", status)
+		}
+	}
+	if len(content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d is documented without a body", status)
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	schema, ok := content[mediaType]
+	if !ok {
+		// Downloads are documented with their full content type
+		if schema, ok = content[contentType]; !ok {
+			return fmt.Errorf("content type %q is not documented for status %d", contentType, status)
+		}
+	}
+	if schema == nil {
+		return nil
+	}
+	value, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	if err := schema.Validate(value); err != nil {
+		issues := issuesOf(err, "")
+		problems := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			problems = append(problems, strings.TrimSpace(fmt.Sprintf("%s: %s %s", issue.Field, issue.Code, issue.Param)))
+		}
+		return fmt.Errorf("body does not match the schema of status %d: %s", status, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// issuesOf flattens a validation error into the failed rules of each value.
+// field names the value validated, "" for a body.
+func issuesOf(err error, field string) []Issue {
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []Issue{{Field: field, Code: "invalid"}}
+	}
+	var issues []Issue
+	collectIssues(validationErr, field, &issues)
+	if len(issues) == 0 {
+		issues = append(issues, Issue{Field: field, Code: "invalid"})
+	}
+	return issues
+}
+
+func collectIssues(err *jsonschema.ValidationError, field string, issues *[]Issue) {
+	at := joinField(field, err.InstanceLocation...)
+	switch k := err.ErrorKind.(type) {
+	case *kind.AnyOf:
+		// A nullable value failing as null says nothing about the value
+		for _, cause := range err.Causes {
+			if t, ok := cause.ErrorKind.(*kind.Type); ok && len(t.Want) == 1 && t.Want[0] == "null" {
+				continue
+			}
+			collectIssues(cause, field, issues)
+		}
+		return
+	case *kind.Required:
+		for _, missing := range k.Missing {
+			*issues = append(*issues, Issue{Field: joinField(at, missing), Code: "required"})
+		}
+	case *kind.AdditionalProperties:
+		for _, property := range k.Properties {
+			*issues = append(*issues, Issue{Field: joinField(at, property), Code: "unknown_field"})
+		}
+	case *kind.Type:
+		*issues = append(*issues, Issue{Field: rootField(at), Code: "type", Param: strings.Join(k.Want, " or ")})
+	case *kind.Enum:
+		values := make([]string, 0, len(k.Want))
+		for _, value := range k.Want {
+			values = append(values, fmt.Sprint(value))
+		}
+		*issues = append(*issues, Issue{Field: rootField(at), Code: "oneof", Param: strings.Join(values, ", ")})
+	case *kind.MinLength:
+		*issues = append(*issues, Issue{Field: rootField(at), Code: "min", Param: strconv.Itoa(k.Want)})
+	case *kind.MaxLength:
+		*issues = append(*issues, Issue{Field: rootField(at), Code: "max", Param: strconv.Itoa(k.Want)})
+	case *kind.Minimum:
+		*issues = append(*issues, Issue{Field: rootField(at), Code: "minimum", Param: k.Want.RatString()})
+	case *kind.Maximum:
+		*issues = append(*issues, Issue{Field: rootField(at), Code: "maximum", Param: k.Want.RatString()})
+	case *kind.Format:
+		*issues = append(*issues, Issue{Field: rootField(at), Code: k.Want})
+	default:
+		if len(err.Causes) == 0 {
+			*issues = append(*issues, Issue{Field: rootField(at), Code: "invalid"})
+		}
+	}
+	for _, cause := range err.Causes {
+		collectIssues(cause, field, issues)
+	}
+}
+
+func joinField(field string, names ...string) string {
+	for _, name := range names {
+		if field == "" {
+			field = name
+		} else {
+			field += "." + name
+		}
+	}
+	return field
+}
+
+// rootField names the body itself when a rule fails on it
+func rootField(field string) string {
+	if field == "" {
+		return "body"
+	}
+	return field
+}
+
+// escapePointer escapes a JSON pointer token
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
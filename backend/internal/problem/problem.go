@@ -0,0 +1,155 @@
+// Package problem writes error responses as RFC 7807 problem details
+// (application/problem+json) and maps errors to them.
+package problem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"auth-go/internal/domain"
+	"auth-go/internal/i18n"
+	"auth-go/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem details
+const ContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the nginx convention for requests whose
+// client disconnected before a response was written.
+const StatusClientClosedRequest = 499
+
+// Codes of problems not raised by domain errors
+const (
+	CodeInternal        = "internal_error"
+	CodeCancelled       = "request_cancelled"
+	CodeTimeout         = "request_timeout"
+	CodeBodyTooLarge    = "body_too_large"
+	CodeMalformedBody   = "malformed_body"
+	CodeEmptyBody       = "empty_body"
+	CodeNotFound        = "not_found"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeRateLimited     = "rate_limited"
+	CodeFeatureDisabled = "feature_disabled"
+)
+
+// statusByKind maps domain error kinds to HTTP status codes
+var statusByKind = map[domain.ErrorKind]int{
+	domain.ErrorKindValidation:   http.StatusBadRequest,
+	domain.ErrorKindUnauthorized: http.StatusUnauthorized,
+	domain.ErrorKindForbidden:    http.StatusForbidden,
+	domain.ErrorKindNotFound:     http.StatusNotFound,
+	domain.ErrorKindConflict:     http.StatusConflict,
+	domain.ErrorKindRateLimited:  http.StatusTooManyRequests,
+}
+
+// Details is a problem details document. Type is always "about:blank", so
+// Title is the status text; Code tells problems apart.
+type Details struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// RequestID correlates the response with the server logs
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+	// Error repeats Detail for clients written before problem details
+	Error string `json:"error"`
+	// Extensions are added as extra members, such as an import report
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// New returns the problem of the current request. The detail is translated
+// through the "errors.<code>" message when the catalogs have one.
+func New(c *gin.Context, status int, code string, detail string) *Details {
+	detail = i18n.For(c.Request.Context()).Text("errors."+code, detail)
+	title := http.StatusText(status)
+	if status == StatusClientClosedRequest {
+		title = "Client Closed Request"
+	}
+	return &Details{
+		Type:      "about:blank",
+		Title:     title,
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: logger.RequestID(c.Request.Context()),
+		Error:     detail,
+	}
+}
+
+// FromError maps err to a problem. Domain errors keep their code and
+// message; any other error becomes an internal error whose message is not
+// shown, clients quote the request ID instead.
+func FromError(c *gin.Context, err error) *Details {
+	var domainErr *domain.Error
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return New(c, StatusClientClosedRequest, CodeCancelled, "Request cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(c, http.StatusServiceUnavailable, CodeTimeout, "Request timed out")
+	case errors.As(err, &tooLarge):
+		return New(c, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, "Request body is too large")
+	case errors.As(err, &domainErr):
+		status, ok := statusByKind[domainErr.Kind]
+		if !ok {
+			status = http.StatusBadRequest
+		}
+		p := New(c, status, domainErr.Code, domainErr.Message)
+		// Wrapping errors add detail, e.g. which option is invalid, in English
+		if extra, ok := strings.CutPrefix(err.Error(), domainErr.Message); ok && extra != "" {
+			p.Detail += extra
+			p.Error = p.Detail
+		}
+		p.Errors = domainErr.Fields
+		return p
+	}
+	return New(c, http.StatusInternalServerError, CodeInternal, "Internal server error, quote the request ID when reporting it")
+}
+
+// Abort writes p and stops the handler chain
+func Abort(c *gin.Context, p *Details) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// MarshalJSON adds the extensions after the standard members
+func (p *Details) MarshalJSON() ([]byte, error) {
+	type details Details
+	body, err := json.Marshal((*details)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+
+	names := make([]string, 0, len(p.Extensions))
+	for name := range p.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(body[:len(body)-1])
+	for _, name := range names {
+		value, err := json.Marshal(p.Extensions[name])
+		if err != nil {
+			return nil, err
+		}
+		key, _ := json.Marshal(name)
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type exportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) domain.ExportJobRepository {
+	return &exportJobRepository{db}
+}
+
+func (r *exportJobRepository) Create(ctx context.Context, job *domain.ExportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *exportJobRepository) FindByID(ctx context.Context, id string) (*domain.ExportJob, error) {
+	var job domain.ExportJob
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepository) Update(ctx context.Context, job *domain.ExportJob) error {
+	return r.db.WithContext(ctx).Save(job).Error
+}
+
+func (r *exportJobRepository) FailUnfinished(ctx context.Context, reason string) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&domain.ExportJob{}).
+		Where("status IN ?", []string{domain.ExportJobPending, domain.ExportJobRunning}).
+		Updates(map[string]interface{}{
+			"status": domain.ExportJobFailed,
+			"error":  reason,
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *exportJobRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*domain.ExportJob, error) {
+	var jobs []*domain.ExportJob
+	err := r.db.WithContext(ctx).Where("expires_at < ?", now).Order("expires_at").Limit(limit).Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *exportJobRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.ExportJob{}).Error
+}
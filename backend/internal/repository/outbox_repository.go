@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) domain.OutboxRepository {
+	return &outboxRepository{db}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, messages ...*domain.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(messages).Error
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*domain.OutboxMessage, error) {
+	var messages []*domain.OutboxMessage
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// SKIP LOCKED lets several workers (or replicas) claim disjoint batches.
+		// Rows stuck in "processing" after their lease expired are picked up again.
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND available_at <= ?) OR (status = ? AND locked_until < ?)",
+				domain.OutboxStatusPending, now, domain.OutboxStatusProcessing, now).
+			Order("available_at").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		ids := make([]uint64, len(messages))
+		for i, m := range messages {
+			ids[i] = m.ID
+		}
+
+		lockedUntil := now.Add(lease)
+		return tx.Model(&domain.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":       domain.OutboxStatusProcessing,
+				"locked_until": lockedUntil,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id uint64) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Model(&domain.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       domain.OutboxStatusSent,
+		"payload":      "{}",
+		"attempts":     gorm.Expr("attempts + 1"),
+		"sent_at":      now,
+		"locked_until": nil,
+		"last_error":   "",
+	}).Error
+}
+
+func (r *outboxRepository) MarkRetry(ctx context.Context, id uint64, attempts int, lastError string, next time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       domain.OutboxStatusPending,
+		"attempts":     attempts,
+		"last_error":   lastError,
+		"available_at": next,
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint64, attempts int, lastError string) error {
+	return r.db.WithContext(ctx).Model(&domain.OutboxMessage{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       domain.OutboxStatusFailed,
+		"attempts":     attempts,
+		"last_error":   lastError,
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *outboxRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Total  int64
+	}
+	err := r.db.WithContext(ctx).Model(&domain.OutboxMessage{}).
+		Select("status, COUNT(*) AS total").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Total
+	}
+	return counts, nil
+}
+
+func (r *outboxRepository) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status = ? AND sent_at < ?", domain.OutboxStatusSent, before).
+		Delete(&domain.OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"auth-go/internal/domain"
+	"context"
 
 	"gorm.io/gorm"
 )
@@ -14,23 +15,29 @@ func NewPasswordResetRepository(db *gorm.DB) domain.PasswordResetRepository {
 	return &passwordResetRepository{db}
 }
 
-func (r *passwordResetRepository) Save(reset *domain.PasswordResetToken) (*domain.PasswordResetToken, error) {
-	err := r.db.Create(reset).Error
+func (r *passwordResetRepository) Save(ctx context.Context, reset *domain.PasswordResetToken) (*domain.PasswordResetToken, error) {
+	err := r.db.WithContext(ctx).Create(reset).Error
 	if err != nil {
 		return nil, err
 	}
 	return reset, nil
 }
 
-func (r *passwordResetRepository) FindByToken(token string) (*domain.PasswordResetToken, error) {
+func (r *passwordResetRepository) FindByToken(ctx context.Context, token string) (*domain.PasswordResetToken, error) {
 	var reset domain.PasswordResetToken
-	err := r.db.Where("token = ?", token).First(&reset).Error
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&reset).Error
 	if err != nil {
 		return nil, err
 	}
 	return &reset, nil
 }
 
-func (r *passwordResetRepository) DeleteByEmail(email string) error {
-	return r.db.Where("email = ?", email).Delete(&domain.PasswordResetToken{}).Error
+func (r *passwordResetRepository) DeleteByToken(ctx context.Context, token string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("token = ?", token).Delete(&domain.PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *passwordResetRepository) DeleteByEmail(ctx context.Context, email string) (int64, error) {
+	result := r.db.WithContext(ctx).Where("email = ?", email).Delete(&domain.PasswordResetToken{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type txManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) domain.TxManager {
+	return &txManager{db}
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(tx domain.Repos) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The regular repositories work unchanged on top of the transaction handle
+		return fn(domain.Repos{
+			Users:          NewUserRepository(tx),
+			PasswordResets: NewPasswordResetRepository(tx),
+			Outbox:         NewOutboxRepository(tx),
+		})
+	})
+}
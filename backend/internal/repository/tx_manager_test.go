@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+)
+
+func TestTxManagerRollsBackOnError(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenTest(t)
+	users := NewUserRepository(db)
+	outbox := NewOutboxRepository(db)
+
+	failure := errors.New("enqueue failed")
+	err := NewTxManager(db).WithinTx(ctx, func(tx domain.Repos) error {
+		if _, err := tx.Users.Save(ctx, &domain.User{Name: "Ana", Email: "ana@example.com", Password: "hash"}); err != nil {
+			return err
+		}
+		message, err := domain.NewOutboxMessage(domain.OutboxKindWelcomeEmail, domain.WelcomeEmailPayload{Email: "ana@example.com"})
+		if err != nil {
+			return err
+		}
+		if err := tx.Outbox.Enqueue(ctx, message); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("WithinTx = %v, want the error of fn", err)
+	}
+
+	if _, err := users.FindByEmail(ctx, "ana@example.com"); err == nil {
+		t.Fatal("the user saved in the rolled back transaction exists")
+	}
+	counts, err := outbox.CountByStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 0 {
+		t.Fatalf("outbox after rollback = %v, want no messages", counts)
+	}
+}
+
+func TestTxManagerCommits(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenTest(t)
+
+	err := NewTxManager(db).WithinTx(ctx, func(tx domain.Repos) error {
+		_, err := tx.Users.Save(ctx, &domain.User{Name: "Ana", Email: "ana@example.com", Password: "hash"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %v", err)
+	}
+	if _, err := NewUserRepository(db).FindByEmail(ctx, "ana@example.com"); err != nil {
+		t.Fatalf("FindByEmail after commit: %v", err)
+	}
+}
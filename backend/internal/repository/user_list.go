@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRelevanceOffset bounds how deep relevance ordered results can be paged
+const maxRelevanceOffset = 1000
+
+// List returns one page of users. Column orderings use keyset pagination:
+// unlike OFFSET the cost of a page does not grow with its depth, because the
+// cursor's sort key and id are used directly as an index range. Relevance
+// ordering and the legacy ?page= use OFFSET instead.
+func (r *userRepository) List(ctx context.Context, q domain.UserListQuery) (*domain.UserPage, error) {
+	base := applyFilter(r.db.WithContext(ctx).Model(&domain.User{}), q.Filter)
+
+	var search userSearch
+	if q.Search != "" {
+		search = r.buildSearch(ctx, q.Search, q.SearchMode)
+		base = r.applySearch(base, q.Search, search)
+	}
+
+	var page *domain.UserPage
+	var err error
+	if q.Sort == domain.UserSortRelevance || (q.Cursor == nil && q.Offset > 0) {
+		page, err = r.listByOffset(base, q, search)
+	} else {
+		page, err = r.listByKeyset(base, q)
+	}
+	if err != nil {
+		return nil, err
+	}
+	page.SearchMode = search.mode
+
+	switch q.Count {
+	case domain.CountNone:
+	case domain.CountApprox:
+		filtered := q.Search != "" || !q.Filter.IsZero()
+		page.Total, page.TotalApprox, err = r.approximateCount(ctx, base, filtered)
+	default:
+		err = base.Session(&gorm.Session{}).Count(&page.Total).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (r *userRepository) listByKeyset(base *gorm.DB, q domain.UserListQuery) (*domain.UserPage, error) {
+	column, err := sortColumn(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walking backwards flips the comparison and order, the rows are
+	// reversed again below
+	backward := q.Cursor != nil && q.Cursor.Backward
+	desc := q.Desc != backward
+
+	query := base.Session(&gorm.Session{})
+	if q.Cursor != nil {
+		value, err := cursorValue(q.Sort, q.Cursor.Value)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		query = query.Where(
+			fmt.Sprintf("%s %s ? OR (%s = ? AND id %s ?)", column, op, column, op),
+			value, value, q.Cursor.ID,
+		)
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	// Fetch one extra row to know whether another page exists
+	var users []*domain.User
+	err = query.Order(column + " " + direction).Order("id " + direction).Limit(q.Limit + 1).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(users) > q.Limit
+	if hasMore {
+		users = users[:q.Limit]
+	}
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	page := &domain.UserPage{Users: users}
+	if len(users) > 0 {
+		first, last := users[0], users[len(users)-1]
+		// Forward: a next page exists if we over-fetched, a previous one if we
+		// started from a cursor. Backward: the other way around.
+		if hasMore || backward {
+			page.Next = newUserCursor(q, last, false)
+		}
+		if (backward && hasMore) || (!backward && q.Cursor != nil) || q.Offset > 0 {
+			page.Prev = newUserCursor(q, first, true)
+		}
+	}
+	return page, nil
+}
+
+func (r *userRepository) listByOffset(base *gorm.DB, q domain.UserListQuery, search userSearch) (*domain.UserPage, error) {
+	offset := q.Offset
+	if q.Cursor != nil {
+		offset = q.Cursor.Offset
+	}
+
+	query := base.Session(&gorm.Session{})
+	if q.Sort == domain.UserSortRelevance {
+		if offset > maxRelevanceOffset {
+			return nil, fmt.Errorf("%w: relevance results are limited to the first %d matches", domain.ErrInvalidListQuery, maxRelevanceOffset)
+		}
+		if search.score.SQL != "" {
+			query = query.Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:                search.score.SQL + " DESC",
+				Vars:               search.score.Vars,
+				WithoutParentheses: true,
+			}})
+		}
+		query = query.Order("id ASC")
+	} else {
+		column, err := sortColumn(q.Sort)
+		if err != nil {
+			return nil, err
+		}
+		direction := "ASC"
+		if q.Desc {
+			direction = "DESC"
+		}
+		query = query.Order(column + " " + direction).Order("id " + direction)
+	}
+
+	var users []*domain.User
+	if err := query.Offset(offset).Limit(q.Limit + 1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := len(users) > q.Limit
+	if hasMore {
+		users = users[:q.Limit]
+	}
+
+	page := &domain.UserPage{Users: users}
+	if q.Sort == domain.UserSortRelevance {
+		if hasMore {
+			page.Next = &domain.UserCursor{Sort: q.Sort, Offset: offset + q.Limit}
+		}
+		if offset > 0 {
+			prev := offset - q.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			page.Prev = &domain.UserCursor{Sort: q.Sort, Offset: prev}
+		}
+		return page, nil
+	}
+
+	// Column orderings continue with keyset cursors from here
+	if len(users) > 0 {
+		if hasMore {
+			page.Next = newUserCursor(q, users[len(users)-1], false)
+		}
+		if offset > 0 {
+			page.Prev = newUserCursor(q, users[0], true)
+		}
+	}
+	return page, nil
+}
+
+// approximateCount reads the row estimate from table statistics when the
+// listing is unfiltered, which is instant on large tables. Filtered listings
+// and SQLite fall back to an exact count.
+func (r *userRepository) approximateCount(ctx context.Context, base *gorm.DB, filtered bool) (int64, bool, error) {
+	if !filtered {
+		var estimate int64
+		var err error
+		switch r.db.Dialector.Name() {
+		case "mysql":
+			err = r.db.WithContext(ctx).Raw(
+				"SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", "users",
+			).Scan(&estimate).Error
+		case "postgres":
+			err = r.db.WithContext(ctx).Raw(
+				"SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE relname = ?", "users",
+			).Scan(&estimate).Error
+		default:
+			err = errors.ErrUnsupported
+		}
+		// Statistics are missing right after creating the table; count instead
+		if err == nil && estimate > 0 {
+			return estimate, true, nil
+		}
+	}
+
+	var total int64
+	err := base.Session(&gorm.Session{}).Count(&total).Error
+	return total, false, err
+}
+
+func newUserCursor(q domain.UserListQuery, user *domain.User, backward bool) *domain.UserCursor {
+	cursor := &domain.UserCursor{Sort: q.Sort, Desc: q.Desc, ID: user.ID, Backward: backward}
+	switch q.Sort {
+	case domain.UserSortName:
+		cursor.Value = user.Name
+	case domain.UserSortEmail:
+		cursor.Value = user.Email
+	default:
+		cursor.Value = user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+func sortColumn(sort string) (string, error) {
+	switch sort {
+	case domain.UserSortCreatedAt, domain.UserSortName, domain.UserSortEmail:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported sort field %q", domain.ErrInvalidListQuery, sort)
+	}
+}
+
+func cursorValue(sort string, value string) (interface{}, error) {
+	if sort != domain.UserSortCreatedAt {
+		return value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+	return t, nil
+}
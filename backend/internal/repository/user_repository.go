@@ -1,82 +1,90 @@
 package repository
 
 import (
+	"context"
+	"strings"
+
 	"auth-go/internal/domain"
 
 	"gorm.io/gorm"
 )
 
 type userRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	search *searchSupport
 }
 
 func NewUserRepository(db *gorm.DB) domain.UserRepository {
-	return &userRepository{db}
+	return &userRepository{db, &searchSupport{}}
 }
 
-func (r *userRepository) Save(user *domain.User) (*domain.User, error) {
-	err := r.db.Create(user).Error
+func (r *userRepository) Save(ctx context.Context, user *domain.User) (*domain.User, error) {
+	err := r.db.WithContext(ctx).Create(user).Error
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (r *userRepository) FindByEmail(email string) (*domain.User, error) {
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindByID(id uint64) (*domain.User, error) {
+func (r *userRepository) FindByID(ctx context.Context, id uint64) (*domain.User, error) {
 	var user domain.User
-	err := r.db.First(&user, id).Error
+	err := r.db.WithContext(ctx).First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *userRepository) FindAll(page int, limit int, search string) ([]*domain.User, int64, error) {
+func (r *userRepository) FindByEmails(ctx context.Context, emails []string) ([]*domain.User, error) {
 	var users []*domain.User
-	var total int64
-
-	// Base query
-	query := r.db.Model(&domain.User{})
-
-	// Search filter
-	if search != "" {
-		// Optimization: Use separate queries per index or use just one if performance is critical for "OR"
-		// LIKE 'val%' uses index. LIKE '%val%' does NOT.
-		// For 3 million rows, we MUST use Prefix match ('val%') or proper FullText search.
-		// using "OR" with two columns can sometimes skip index utilization depending on MySQL version.
-		// For now, let's optimize to prefix match on Name OR Email (email already indexed).
-		searchPattern := search + "%"
-		query = query.Where("name LIKE ? OR email LIKE ?", searchPattern, searchPattern)
+	if len(emails) == 0 {
+		return users, nil
 	}
-
-	// Count total records (before pagination)
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Pagination
-	offset := (page - 1) * limit
-	err := query.Offset(offset).Limit(limit).Find(&users).Error
+	err := r.db.WithContext(ctx).Where("email IN ?", emails).Find(&users).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-
-	return users, total, nil
+	return users, nil
 }
 
-func (r *userRepository) Update(user *domain.User) (*domain.User, error) {
-	err := r.db.Save(user).Error
+func (r *userRepository) Update(ctx context.Context, user *domain.User) (*domain.User, error) {
+	err := r.db.WithContext(ctx).Save(user).Error
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
+
+// prefixSearch matches name or email starting with search, case-insensitively
+// on every driver. MySQL's default collation and SQLite's LIKE are already
+// case-insensitive; PostgreSQL needs ILIKE (backed by trigram indexes).
+//
+// LIKE 'val%' uses the indexes, LIKE '%val%' does not, so on millions of rows
+// this stays a prefix match; see user_search.go for word and fuzzy matching.
+func (r *userRepository) prefixSearch(query *gorm.DB, search string) *gorm.DB {
+	searchPattern := escapeLike(search) + "%"
+
+	switch r.db.Dialector.Name() {
+	case "postgres":
+		return query.Where("name ILIKE ? OR email ILIKE ?", searchPattern, searchPattern)
+	case "sqlite":
+		// SQLite has no default escape character
+		return query.Where(`name LIKE ? ESCAPE '\' OR email LIKE ? ESCAPE '\'`, searchPattern, searchPattern)
+	default:
+		return query.Where("name LIKE ? OR email LIKE ?", searchPattern, searchPattern)
+	}
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+func saveUsers(t *testing.T, repo domain.UserRepository, users ...*domain.User) {
+	t.Helper()
+	for _, user := range users {
+		if user.Password == "" {
+			user.Password = "hash"
+		}
+		if _, err := repo.Save(context.Background(), user); err != nil {
+			t.Fatalf("saving %s: %v", user.Email, err)
+		}
+	}
+}
+
+func userNames(users []*domain.User) []string {
+	names := make([]string, len(users))
+	for i, user := range users {
+		names[i] = user.Name
+	}
+	return names
+}
+
+func equalNames(got []*domain.User, want ...string) bool {
+	names := userNames(got)
+	if len(names) != len(want) {
+		return false
+	}
+	for i := range names {
+		if names[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUserRepositorySaveAndFindByEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(database.OpenTest(t))
+
+	saved, err := repo.Save(ctx, &domain.User{Name: "Ana", Email: "ana@example.com", Password: "hash"})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if saved.ID == 0 {
+		t.Fatal("Save did not set the ID")
+	}
+
+	found, err := repo.FindByEmail(ctx, "ana@example.com")
+	if err != nil {
+		t.Fatalf("FindByEmail: %v", err)
+	}
+	if found.ID != saved.ID || found.Name != "Ana" {
+		t.Fatalf("FindByEmail = %+v, want the saved user", found)
+	}
+	// Column defaults come from the migrations
+	if found.Locale != "en" || found.Role != domain.RoleUser || found.Status != domain.StatusActive {
+		t.Fatalf("defaults = %q, %q, %q; want en, user, active", found.Locale, found.Role, found.Status)
+	}
+
+	if _, err := repo.FindByEmail(ctx, "nobody@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("FindByEmail of an unknown email = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := repo.Save(ctx, &domain.User{Name: "Other", Email: "ana@example.com", Password: "hash"}); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("Save of a duplicate email = %v, want ErrDuplicatedKey", err)
+	}
+}
+
+func TestUserRepositoryListCursors(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(database.OpenTest(t))
+	saveUsers(t, repo,
+		&domain.User{Name: "Dewi", Email: "dewi@example.com"},
+		&domain.User{Name: "Ana", Email: "ana@example.com"},
+		&domain.User{Name: "Eka", Email: "eka@example.com"},
+		&domain.User{Name: "Citra", Email: "citra@example.com"},
+		&domain.User{Name: "Budi", Email: "budi@example.com"},
+	)
+
+	q := domain.UserListQuery{Sort: domain.UserSortName, Limit: 2, Count: domain.CountExact}
+	first, err := repo.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalNames(first.Users, "Ana", "Budi") || first.Total != 5 {
+		t.Fatalf("first page = %v (total %d), want [Ana Budi] (total 5)", userNames(first.Users), first.Total)
+	}
+	if first.Prev != nil || first.Next == nil {
+		t.Fatalf("first page cursors: prev %v, next %v", first.Prev, first.Next)
+	}
+
+	q.Cursor = first.Next
+	second, err := repo.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalNames(second.Users, "Citra", "Dewi") || second.Prev == nil || second.Next == nil {
+		t.Fatalf("second page = %v, prev %v, next %v", userNames(second.Users), second.Prev, second.Next)
+	}
+
+	q.Cursor = second.Next
+	last, err := repo.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalNames(last.Users, "Eka") || last.Next != nil {
+		t.Fatalf("last page = %v, next %v", userNames(last.Users), last.Next)
+	}
+
+	q.Cursor = second.Prev
+	back, err := repo.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalNames(back.Users, "Ana", "Budi") || back.Prev != nil {
+		t.Fatalf("page before the second = %v, prev %v", userNames(back.Users), back.Prev)
+	}
+}
+
+func TestUserRepositoryListCreatedAtDescending(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(database.OpenTest(t))
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	saveUsers(t, repo,
+		&domain.User{Name: "Ana", Email: "ana@example.com", CreatedAt: start},
+		&domain.User{Name: "Budi", Email: "budi@example.com", CreatedAt: start.Add(time.Hour)},
+		&domain.User{Name: "Citra", Email: "citra@example.com", CreatedAt: start.Add(2 * time.Hour)},
+	)
+
+	q := domain.UserListQuery{Sort: domain.UserSortCreatedAt, Desc: true, Limit: 2, Count: domain.CountNone}
+	first, err := repo.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalNames(first.Users, "Citra", "Budi") || first.Next == nil {
+		t.Fatalf("first page = %v, next %v", userNames(first.Users), first.Next)
+	}
+
+	q.Cursor = first.Next
+	second, err := repo.List(ctx, q)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalNames(second.Users, "Ana") || second.Next != nil {
+		t.Fatalf("second page = %v, next %v", userNames(second.Users), second.Next)
+	}
+}
+
+func TestUserRepositoryListSearch(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(database.OpenTest(t))
+	saveUsers(t, repo,
+		&domain.User{Name: "Anita", Email: "anita@example.com"},
+		&domain.User{Name: "Budi", Email: "anton@example.com"},
+		&domain.User{Name: "Citra", Email: "citra@example.com"},
+		&domain.User{Name: "a_b", Email: "underscore@example.com"},
+		&domain.User{Name: "axb", Email: "letter@example.com"},
+	)
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		// Names and emails match by prefix, case-insensitively
+		{"an", []string{"Anita", "Budi"}},
+		{"CIT", []string{"Citra"}},
+		{"citra@", []string{"Citra"}},
+		// LIKE wildcards in the input are matched literally
+		{"a_", []string{"a_b"}},
+		{"zzz", nil},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(ctx, domain.UserListQuery{Search: tt.search, Sort: domain.UserSortName, Limit: 10, Count: domain.CountExact})
+		if err != nil {
+			t.Fatalf("List(%q): %v", tt.search, err)
+		}
+		if !equalNames(page.Users, tt.want...) || page.Total != int64(len(tt.want)) {
+			t.Errorf("List(%q) = %v (total %d), want %v", tt.search, userNames(page.Users), page.Total, tt.want)
+		}
+		if page.SearchMode != domain.SearchPrefix {
+			t.Errorf("List(%q) search mode = %q, want %q", tt.search, page.SearchMode, domain.SearchPrefix)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchSupport caches whether the full-text index exists, detected on first use
+type searchSupport struct {
+	mu       sync.Mutex
+	checked  bool
+	fullText bool
+}
+
+// userSearch is a search condition plus the expression used to rank matches
+type userSearch struct {
+	mode  string
+	where clause.Expr
+	// score is empty when the mode has no meaningful relevance
+	score clause.Expr
+}
+
+var searchTermPattern = regexp.MustCompile(`[\pL\pN]+`)
+
+// buildSearch picks the search strategy for mode. Full-text and fuzzy
+// matching fall back to the prefix search when the driver or the database
+// lacks the required indexes.
+func (r *userRepository) buildSearch(ctx context.Context, search string, mode string) userSearch {
+	driver := r.db.Dialector.Name()
+	terms := searchTermPattern.FindAllString(strings.ToLower(search), -1)
+
+	if mode == "" || mode == domain.SearchAuto {
+		mode = domain.SearchPrefix
+		// Email-like input is best served by the prefix index on email
+		if !strings.Contains(search, "@") && r.hasFullText(ctx) {
+			mode = domain.SearchFullText
+		}
+	}
+
+	switch mode {
+	case domain.SearchFullText:
+		if !r.hasFullText(ctx) || len(terms) == 0 {
+			break
+		}
+		switch driver {
+		case "mysql":
+			// InnoDB ignores words shorter than innodb_ft_min_token_size (3)
+			var words []string
+			for _, term := range terms {
+				if len([]rune(term)) >= 3 {
+					words = append(words, "+"+term+"*")
+				}
+			}
+			if len(words) == 0 {
+				break
+			}
+			against := strings.Join(words, " ")
+			return userSearch{
+				mode:  domain.SearchFullText,
+				where: clause.Expr{SQL: "MATCH(name, email) AGAINST (? IN BOOLEAN MODE)", Vars: []interface{}{against}},
+				score: clause.Expr{SQL: "MATCH(name, email) AGAINST (? IN BOOLEAN MODE)", Vars: []interface{}{against}},
+			}
+		case "postgres":
+			words := make([]string, len(terms))
+			for i, term := range terms {
+				words[i] = term + ":*"
+			}
+			tsquery := strings.Join(words, " & ")
+			return userSearch{
+				mode:  domain.SearchFullText,
+				where: clause.Expr{SQL: postgresSearchVector + " @@ to_tsquery('simple', ?)", Vars: []interface{}{tsquery}},
+				score: clause.Expr{SQL: "ts_rank(" + postgresSearchVector + ", to_tsquery('simple', ?))", Vars: []interface{}{tsquery}},
+			}
+		}
+
+	case domain.SearchFuzzy:
+		term := strings.Join(terms, " ")
+		if term == "" {
+			break
+		}
+		switch driver {
+		case "postgres":
+			// pg_trgm similarity, served by the trigram indexes
+			return userSearch{
+				mode:  domain.SearchFuzzy,
+				where: clause.Expr{SQL: "(name % ? OR email % ?)", Vars: []interface{}{term, term}},
+				score: clause.Expr{SQL: "GREATEST(similarity(name, ?), similarity(email, ?))", Vars: []interface{}{term, term}},
+			}
+		case "mysql":
+			// Names whose first word sounds like the first term. This cannot use
+			// an index, so it relies on the request timeout on large tables.
+			return userSearch{
+				mode: domain.SearchFuzzy,
+				where: clause.Expr{
+					SQL:  "(SOUNDEX(name) LIKE CONCAT(SOUNDEX(?), '%') OR email LIKE ?)",
+					Vars: []interface{}{terms[0], escapeLike(terms[0]) + "%"},
+				},
+			}
+		}
+	}
+
+	return userSearch{mode: domain.SearchPrefix}
+}
+
+// postgresSearchVector must match the expression of idx_users_search_tsv
+const postgresSearchVector = "to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(email, ''))"
+
+// applySearch adds the search condition to query
+func (r *userRepository) applySearch(query *gorm.DB, search string, s userSearch) *gorm.DB {
+	if s.mode == domain.SearchPrefix {
+		return r.prefixSearch(query, search)
+	}
+	return query.Where(s.where)
+}
+
+// hasFullText reports whether the users table has a full-text index
+func (r *userRepository) hasFullText(ctx context.Context) bool {
+	r.search.mu.Lock()
+	defer r.search.mu.Unlock()
+
+	if !r.search.checked {
+		var count int64
+		var err error
+		switch r.db.Dialector.Name() {
+		case "mysql":
+			err = r.db.WithContext(ctx).Raw(
+				"SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_TYPE = ?",
+				"users", "FULLTEXT",
+			).Scan(&count).Error
+		case "postgres":
+			err = r.db.WithContext(ctx).Raw(
+				"SELECT COUNT(*) FROM pg_indexes WHERE tablename = ? AND indexname = ?",
+				"users", "idx_users_search_tsv",
+			).Scan(&count).Error
+		}
+		// Retry on the next search if detection failed, e.g. on a cancelled request
+		r.search.checked = err == nil
+		r.search.fullText = err == nil && count > 0
+	}
+	return r.search.fullText
+}
+
+// applyFilter adds the filter conditions to query
+func applyFilter(query *gorm.DB, f domain.UserFilter) *gorm.DB {
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.Role != "" {
+		query = query.Where("role = ?", f.Role)
+	}
+	if f.Verified != nil {
+		if *f.Verified {
+			query = query.Where("email_verified_at IS NOT NULL")
+		} else {
+			query = query.Where("email_verified_at IS NULL")
+		}
+	}
+	if f.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		query = query.Where("created_at < ?", *f.CreatedTo)
+	}
+	return query
+}
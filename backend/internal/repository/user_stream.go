@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+func (r *userRepository) Count(ctx context.Context, q domain.UserListQuery) (int64, error) {
+	var total int64
+	err := r.matching(ctx, q).Count(&total).Error
+	return total, err
+}
+
+// Stream walks the matching users by primary key. Every batch is a separate
+// short query, so no connection or snapshot is held while fn writes out a
+// batch, however large the export.
+func (r *userRepository) Stream(ctx context.Context, q domain.UserListQuery, batchSize int, fn func(users []*domain.User) error) error {
+	base := r.matching(ctx, q)
+
+	var lastID uint64
+	for {
+		var users []*domain.User
+		err := base.Session(&gorm.Session{}).Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&users).Error
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		if err := fn(users); err != nil {
+			return err
+		}
+		if len(users) < batchSize {
+			return nil
+		}
+		lastID = users[len(users)-1].ID
+	}
+}
+
+// matching applies the search and filter of q, ignoring sort and paging
+func (r *userRepository) matching(ctx context.Context, q domain.UserListQuery) *gorm.DB {
+	query := applyFilter(r.db.WithContext(ctx).Model(&domain.User{}), q.Filter)
+	if q.Search != "" {
+		query = r.applySearch(query, q.Search, r.buildSearch(ctx, q.Search, q.SearchMode))
+	}
+	return query
+}
@@ -0,0 +1,167 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"auth-go/internal/domain"
+	"auth-go/pkg/utils"
+
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Fixture is the content of a YAML or JSON fixture file
+type Fixture struct {
+	Users []FixtureUser `yaml:"users" json:"users"`
+}
+
+// FixtureUser describes one user. Existing users with the same email are
+// updated, so loading a fixture twice is harmless.
+type FixtureUser struct {
+	Name  string `yaml:"name" json:"name"`
+	Email string `yaml:"email" json:"email"`
+	// Password is hashed on load; PasswordHash is stored as is
+	Password     string     `yaml:"password" json:"password"`
+	PasswordHash string     `yaml:"password_hash" json:"password_hash"`
+	Locale       string     `yaml:"locale" json:"locale"`
+	Role         string     `yaml:"role" json:"role"`
+	Status       string     `yaml:"status" json:"status"`
+	Organization string     `yaml:"organization" json:"organization"`
+	Verified     bool       `yaml:"verified" json:"verified"`
+	CreatedAt    *time.Time `yaml:"created_at" json:"created_at"`
+}
+
+// ReadFixture parses a .yaml, .yml or .json fixture file, rejecting unknown
+// keys to catch typos
+func ReadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&fixture)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&fixture)
+	default:
+		return nil, fmt.Errorf("%s: fixtures must be .yaml, .yml or .json files", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for i, user := range fixture.Users {
+		if err := user.validate(); err != nil {
+			return nil, fmt.Errorf("%s: user %d (%s): %w", path, i+1, user.Email, err)
+		}
+	}
+	return &fixture, nil
+}
+
+func (u FixtureUser) validate() error {
+	switch {
+	case u.Name == "" || u.Email == "":
+		return errors.New("name and email are required")
+	case u.Password == "" && u.PasswordHash == "":
+		return errors.New("password or password_hash is required")
+	case u.Password != "" && u.PasswordHash != "":
+		return errors.New("set either password or password_hash, not both")
+	}
+	if u.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+			return errors.New("password_hash must be a bcrypt hash")
+		}
+	}
+	switch u.Role {
+	case "", domain.RoleUser, domain.RoleAdmin:
+	default:
+		return fmt.Errorf("unknown role %q", u.Role)
+	}
+	switch u.Status {
+	case "", domain.StatusActive, domain.StatusSuspended:
+	default:
+		return fmt.Errorf("unknown status %q", u.Status)
+	}
+	return nil
+}
+
+// LoadFixtures creates or updates the users of every fixture in one
+// transaction and returns how many were written
+func LoadFixtures(ctx context.Context, db *gorm.DB, fixtures []*Fixture, defaultLocale string) (int, error) {
+	// Fixtures tend to share a few demo passwords, hash each once
+	hashes := make(map[string]string)
+	written := 0
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, fixture := range fixtures {
+			for _, fu := range fixture.Users {
+				hash := fu.PasswordHash
+				if hash == "" {
+					if hash = hashes[fu.Password]; hash == "" {
+						var err error
+						if hash, err = utils.HashPassword(fu.Password); err != nil {
+							return err
+						}
+						hashes[fu.Password] = hash
+					}
+				}
+
+				var user domain.User
+				err := tx.Where("email = ?", fu.Email).First(&user).Error
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+
+				user.Name = fu.Name
+				user.Email = fu.Email
+				user.Password = hash
+				user.Locale = valueOr(fu.Locale, defaultLocale)
+				user.Role = valueOr(fu.Role, domain.RoleUser)
+				user.Status = valueOr(fu.Status, domain.StatusActive)
+				user.Organization = fu.Organization
+				if fu.CreatedAt != nil {
+					user.CreatedAt = *fu.CreatedAt
+				}
+				switch {
+				case fu.Verified && user.EmailVerifiedAt == nil:
+					now := time.Now()
+					user.EmailVerifiedAt = &now
+				case !fu.Verified:
+					user.EmailVerifiedAt = nil
+				}
+
+				if err := tx.Save(&user).Error; err != nil {
+					return fmt.Errorf("%s: %w", fu.Email, err)
+				}
+				written++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func valueOr(value string, fallback string) string {
+	if value != "" {
+		return value
+	}
+	return fallback
+}
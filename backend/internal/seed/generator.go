@@ -0,0 +1,170 @@
+// Package seed generates realistic users and loads fixture files, for
+// development databases, load tests and demos.
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth-go/internal/domain"
+)
+
+// Weights is a weighted choice between values, parsed from "a=3,b=1".
+// Values without a weight count as 1.
+type Weights struct {
+	Values  []string
+	Weights []float64
+	total   float64
+}
+
+// ParseWeights parses a comma separated list of value=weight pairs
+func ParseWeights(spec string) (Weights, error) {
+	var w Weights
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		value, weightText, hasWeight := strings.Cut(item, "=")
+		weight := 1.0
+		if hasWeight {
+			var err error
+			weight, err = strconv.ParseFloat(strings.TrimSpace(weightText), 64)
+			if err != nil || weight < 0 {
+				return w, fmt.Errorf("invalid weight in %q", item)
+			}
+		}
+		w.Values = append(w.Values, strings.TrimSpace(value))
+		w.Weights = append(w.Weights, weight)
+		w.total += weight
+	}
+	if len(w.Values) > 0 && w.total == 0 {
+		return w, fmt.Errorf("weights in %q add up to zero", spec)
+	}
+	return w, nil
+}
+
+// Pick returns a value with probability proportional to its weight, or ""
+// when there are no values
+func (w Weights) Pick(r *rand.Rand) string {
+	if len(w.Values) == 0 {
+		return ""
+	}
+	n := r.Float64() * w.total
+	for i, weight := range w.Weights {
+		if n < weight {
+			return w.Values[i]
+		}
+		n -= weight
+	}
+	return w.Values[len(w.Values)-1]
+}
+
+// String formats the weights back into the ParseWeights syntax
+func (w Weights) String() string {
+	parts := make([]string, len(w.Values))
+	for i, value := range w.Values {
+		parts[i] = value + "=" + strconv.FormatFloat(w.Weights[i], 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// GeneratorOptions shape the generated users. Together with Seed they fully
+// determine the output, so they are also what a checkpoint is tied to.
+type GeneratorOptions struct {
+	Seed    uint64
+	Locales Weights
+	Roles   Weights
+	// Orgs is empty to leave users without an organization
+	Orgs Weights
+	// Verified and Suspended are the fractions of such users, from 0 to 1
+	Verified  float64
+	Suspended float64
+	// Users are created at random times in the CreatedWithin before
+	// CreatedUntil. Run sets a zero CreatedUntil to today's midnight, or to
+	// the one of the run it resumes.
+	CreatedUntil  time.Time
+	CreatedWithin time.Duration
+}
+
+// Validate checks the options against the values the rest of the app accepts
+func (o GeneratorOptions) Validate() error {
+	for _, locale := range o.Locales.Values {
+		if _, ok := localeNames[locale]; !ok {
+			return fmt.Errorf("no names for locale %q, available: %s", locale, strings.Join(Locales(), ", "))
+		}
+	}
+	for _, role := range o.Roles.Values {
+		if role != domain.RoleUser && role != domain.RoleAdmin {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+	if o.Verified < 0 || o.Verified > 1 || o.Suspended < 0 || o.Suspended > 1 {
+		return fmt.Errorf("verified and suspended must be fractions between 0 and 1")
+	}
+	if o.CreatedWithin <= 0 {
+		return fmt.Errorf("created-within must be positive")
+	}
+	return nil
+}
+
+// Generator derives user n from the seed and n alone, so any range of users
+// can be generated independently, in parallel and again after a restart
+type Generator struct {
+	opts     GeneratorOptions
+	password string
+}
+
+// NewGenerator returns a Generator giving every user the same password hash
+func NewGenerator(opts GeneratorOptions, passwordHash string) *Generator {
+	return &Generator{opts: opts, password: passwordHash}
+}
+
+// User returns the n-th generated user
+func (g *Generator) User(n int) *domain.User {
+	r := rand.New(rand.NewPCG(g.opts.Seed, uint64(n)))
+
+	locale := g.opts.Locales.Pick(r)
+	names := localeNames[locale]
+	first := names.first[r.IntN(len(names.first))]
+	last := names.last[r.IntN(len(names.last))]
+	emailDomain := emailDomains[r.IntN(len(emailDomains))]
+
+	createdAt := g.opts.CreatedUntil.Add(-time.Duration(r.Int64N(int64(g.opts.CreatedWithin)))).UTC()
+	user := &domain.User{
+		Name: first + " " + last,
+		// The number keeps emails unique however many users share a name
+		Email:        fmt.Sprintf("%s.%s.%d@%s", emailLocalPart(first), emailLocalPart(last), n, emailDomain),
+		Password:     g.password,
+		Locale:       locale,
+		Role:         g.opts.Roles.Pick(r),
+		Status:       domain.StatusActive,
+		Organization: g.opts.Orgs.Pick(r),
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+	}
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+
+	// Always draw both numbers so later fields do not depend on earlier ones
+	verified, suspended := r.Float64(), r.Float64()
+	if verified < g.opts.Verified {
+		verifiedAt := createdAt.Add(time.Duration(r.Int64N(int64(48 * time.Hour))))
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	if suspended < g.opts.Suspended {
+		user.Status = domain.StatusSuspended
+	}
+	return user
+}
+
+// fingerprint identifies the generated data set for checkpoints
+func (o GeneratorOptions) fingerprint(count int) string {
+	return fmt.Sprintf("seed=%d count=%d locales=%s roles=%s orgs=%s verified=%g suspended=%g until=%s within=%s",
+		o.Seed, count, o.Locales, o.Roles, o.Orgs, o.Verified, o.Suspended,
+		o.CreatedUntil.UTC().Format(time.RFC3339), o.CreatedWithin)
+}
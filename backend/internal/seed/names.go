@@ -0,0 +1,50 @@
+package seed
+
+import "strings"
+
+// localeNames holds common given and family names per locale
+var localeNames = map[string]struct {
+	first []string
+	last  []string
+}{
+	"en": {
+		first: []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "William", "Elizabeth", "David", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen", "Daniel", "Emily", "Matthew", "Olivia"},
+		last:  []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Miller", "Davis", "Wilson", "Anderson", "Taylor", "Thomas", "Moore", "Jackson", "Martin", "Lee", "Thompson", "White", "Harris", "Clark", "Lewis", "Walker", "Hall", "Young", "King"},
+	},
+	"id": {
+		first: []string{"Budi", "Siti", "Agus", "Dewi", "Andi", "Rina", "Eko", "Sri", "Hendra", "Wulan", "Rizky", "Putri", "Dimas", "Ayu", "Fajar", "Indah", "Bayu", "Lestari", "Yusuf", "Nur", "Arif", "Fitri", "Teguh", "Maya"},
+		last:  []string{"Santoso", "Wijaya", "Saputra", "Hidayat", "Nugroho", "Pratama", "Kusuma", "Setiawan", "Wibowo", "Susanto", "Gunawan", "Purnomo", "Halim", "Siregar", "Nasution", "Lubis", "Simanjuntak", "Hakim", "Rahman", "Putra", "Sari", "Utami", "Firmansyah", "Kurniawan"},
+	},
+	"de": {
+		first: []string{"Lukas", "Anna", "Leon", "Marie", "Felix", "Sophie", "Jonas", "Lena", "Paul", "Hannah", "Maximilian", "Emma", "Jürgen", "Käthe", "Niklas", "Laura", "Tobias", "Julia", "Florian", "Katrin"},
+		last:  []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann", "Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann"},
+	},
+	"es": {
+		first: []string{"Alejandro", "Lucía", "Daniel", "María", "Pablo", "Paula", "Javier", "Sofía", "Sergio", "Carmen", "Adrián", "Laura", "Álvaro", "Marta", "Diego", "Elena", "Iván", "Ana", "Raúl", "Cristina"},
+		last:  []string{"García", "Fernández", "González", "Rodríguez", "López", "Martínez", "Sánchez", "Pérez", "Gómez", "Martín", "Jiménez", "Ruiz", "Hernández", "Díaz", "Moreno", "Muñoz", "Álvarez", "Romero", "Alonso", "Navarro"},
+	},
+	"fr": {
+		first: []string{"Lucas", "Camille", "Hugo", "Léa", "Louis", "Chloé", "Gabriel", "Manon", "Arthur", "Inès", "Jules", "Zoé", "Théo", "Élise", "Nathan", "Margaux", "Raphaël", "Juliette", "Mathis", "Océane"},
+		last:  []string{"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau", "Simon", "Laurent", "Lefèvre", "Michel", "Garcia", "David", "Bertrand", "Roux", "Vincent", "Fournier"},
+	},
+}
+
+// emailDomains are reserved for documentation (RFC 2606), so generated
+// addresses can never reach a real mailbox
+var emailDomains = []string{"example.com", "example.org", "example.net", "mail.example.com"}
+
+// Locales returns the locales with built-in names
+func Locales() []string {
+	return []string{"en", "id", "de", "es", "fr"}
+}
+
+var emailFold = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "ae", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "î", "i", "ï", "i", "ó", "o", "ô", "o", "ö", "oe", "ú", "u", "ù", "u",
+	"û", "u", "ü", "ue", "ñ", "n", "ç", "c", "ß", "ss", " ", "",
+)
+
+// emailLocalPart lowercases a name and folds the accents away
+func emailLocalPart(name string) string {
+	return emailFold.Replace(strings.ToLower(name))
+}
@@ -0,0 +1,247 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"auth-go/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bindParamLimits is the most bind parameters one statement may carry on
+// each driver. SQLite has allowed 32766 since 3.32.
+var bindParamLimits = map[string]int{
+	"mysql":    65535,
+	"postgres": 65535,
+	"sqlite":   32766,
+}
+
+// MaxBatchSize returns the most users one INSERT on db can carry: the bind
+// parameter limit of its driver divided by the columns of a user, e.g. 2978
+// on SQLite with 11 columns
+func MaxBatchSize(db *gorm.DB) (int, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&domain.User{}); err != nil {
+		return 0, err
+	}
+	limit, ok := bindParamLimits[db.Dialector.Name()]
+	if !ok {
+		limit = bindParamLimits["sqlite"]
+	}
+	return limit / len(stmt.Schema.DBNames), nil
+}
+
+// RunOptions control how generated users are written
+type RunOptions struct {
+	Count       int
+	BatchSize   int
+	Concurrency int
+	// Checkpoint is the file recording progress, empty to disable resuming
+	Checkpoint string
+	// Fresh ignores an existing checkpoint and starts from the first user
+	Fresh bool
+}
+
+// Result summarises a run
+type Result struct {
+	// Inserted excludes users that already existed, e.g. when resuming
+	Inserted int64
+	Resumed  int
+	Elapsed  time.Duration
+}
+
+// checkpoint is the progress of a run. Users below Done are all written;
+// batches completed above it are written again on resume, which is
+// harmless because existing emails are skipped.
+type checkpoint struct {
+	Fingerprint string `json:"fingerprint"`
+	// CreatedUntil is the resolved GeneratorOptions.CreatedUntil, so a run
+	// using the default resumes with it on a later day
+	CreatedUntil time.Time `json:"created_until"`
+	Done         int       `json:"done"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// now is replaced by tests
+var now = time.Now
+
+// Run inserts opts.Count generated users in batches on opts.Concurrency
+// workers. The first failing batch stops the run and is returned as the
+// error, with the checkpoint left at the last fully written position.
+func Run(ctx context.Context, db *gorm.DB, gen *Generator, opts RunOptions) (*Result, error) {
+	maxBatchSize, err := MaxBatchSize(db)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BatchSize <= 0 || opts.BatchSize > maxBatchSize {
+		return nil, fmt.Errorf("batch size must be between 1 and %d on %s", maxBatchSize, db.Dialector.Name())
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
+	start := time.Now()
+	var cp *checkpoint
+	if opts.Checkpoint != "" && !opts.Fresh {
+		if cp, err = readCheckpoint(opts.Checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	genOpts := gen.opts
+	if genOpts.CreatedUntil.IsZero() {
+		if cp != nil && !cp.CreatedUntil.IsZero() {
+			genOpts.CreatedUntil = cp.CreatedUntil
+		} else {
+			// Midnight keeps runs on the same day reproducible
+			genOpts.CreatedUntil = now().UTC().Truncate(24 * time.Hour)
+		}
+		gen = NewGenerator(genOpts, gen.password)
+	}
+	progress := checkpoint{Fingerprint: genOpts.fingerprint(opts.Count), CreatedUntil: genOpts.CreatedUntil}
+
+	done := 0
+	if cp != nil {
+		if cp.Fingerprint != progress.Fingerprint {
+			return nil, fmt.Errorf("checkpoint %s belongs to a run with different options (%s), remove it or start fresh", opts.Checkpoint, cp.Fingerprint)
+		}
+		done = cp.Done
+	}
+	result := &Result{Resumed: done}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan int)
+	finished := make(chan batchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for first := range batches {
+				inserted, err := insertBatch(ctx, db, gen, first, min(first+opts.BatchSize, opts.Count))
+				finished <- batchResult{first, inserted, err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(batches)
+		for first := done; first < opts.Count; first += opts.BatchSize {
+			select {
+			case batches <- first:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	// Advance the checkpoint over batches that completed in order
+	completed := make(map[int]bool)
+	lastLog := time.Now()
+	var runErr error
+	for res := range finished {
+		if res.err != nil {
+			if runErr == nil {
+				runErr = fmt.Errorf("batch starting at user %d: %w", res.first, res.err)
+				cancel()
+			}
+			continue
+		}
+		result.Inserted += res.inserted
+		completed[res.first] = true
+		for completed[done] {
+			delete(completed, done)
+			done = min(done+opts.BatchSize, opts.Count)
+		}
+
+		if time.Since(lastLog) >= 5*time.Second || done == opts.Count {
+			lastLog = time.Now()
+			log.Printf("Seeded %d/%d users (%s elapsed)", done, opts.Count, time.Since(start).Round(time.Second))
+			if err := writeCheckpoint(opts.Checkpoint, progress, done); err != nil {
+				log.Printf("Failed to write checkpoint: %v", err)
+			}
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	if err := writeCheckpoint(opts.Checkpoint, progress, done); err != nil {
+		log.Printf("Failed to write checkpoint: %v", err)
+	}
+	if runErr == nil {
+		runErr = ctx.Err()
+	}
+	return result, runErr
+}
+
+type batchResult struct {
+	first    int
+	inserted int64
+	err      error
+}
+
+// insertBatch writes users [first, last). Users are numbered from 1 in
+// their emails to match the seeder's historical "user1" numbering.
+func insertBatch(ctx context.Context, db *gorm.DB, gen *Generator, first int, last int) (int64, error) {
+	users := make([]*domain.User, 0, last-first)
+	for n := first; n < last; n++ {
+		users = append(users, gen.User(n+1))
+	}
+	result := db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&users)
+	return result.RowsAffected, result.Error
+}
+
+func readCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// writeCheckpoint replaces the checkpoint atomically, so a crash while
+// writing cannot corrupt it
+func writeCheckpoint(path string, cp checkpoint, done int) error {
+	if path == "" {
+		return nil
+	}
+	cp.Done, cp.UpdatedAt = done, time.Now().UTC()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
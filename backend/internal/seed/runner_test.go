@@ -0,0 +1,89 @@
+package seed
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+)
+
+func TestRunFullBatchesOnSQLite(t *testing.T) {
+	db := database.OpenTest(t)
+
+	maxBatchSize, err := MaxBatchSize(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locales, err := ParseWeights("en=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen := NewGenerator(GeneratorOptions{
+		Seed:          1,
+		Locales:       locales,
+		CreatedUntil:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		CreatedWithin: 24 * time.Hour,
+	}, "hash")
+
+	if _, err := Run(context.Background(), db, gen, RunOptions{Count: 1, BatchSize: maxBatchSize + 1}); err == nil {
+		t.Fatalf("Run accepted a batch size over %d", maxBatchSize)
+	}
+
+	// A full batch must stay within SQLite's bind parameter limit
+	count := maxBatchSize + 10
+	result, err := Run(context.Background(), db, gen, RunOptions{Count: count, BatchSize: maxBatchSize, Concurrency: 1})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var total int64
+	if err := db.Model(&domain.User{}).Count(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	if result.Inserted != int64(count) || total != int64(count) {
+		t.Fatalf("inserted %d, %d users in the table; want %d", result.Inserted, total, count)
+	}
+}
+
+func TestRunResumesWithTheDefaultDateOnALaterDay(t *testing.T) {
+	db := database.OpenTest(t)
+	path := filepath.Join(t.TempDir(), "seed.checkpoint.json")
+	locales, err := ParseWeights("en=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CreatedUntil is left to its default, today's midnight
+	gen := NewGenerator(GeneratorOptions{Seed: 1, Locales: locales, CreatedWithin: 24 * time.Hour}, "hash")
+	opts := RunOptions{Count: 10, BatchSize: 5, Concurrency: 1, Checkpoint: path}
+
+	firstDay := time.Date(2026, 3, 1, 23, 50, 0, 0, time.UTC)
+	t.Cleanup(func() { now = time.Now })
+	now = func() time.Time { return firstDay }
+	if _, err := Run(context.Background(), db, gen, opts); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	now = func() time.Time { return firstDay.Add(time.Hour) }
+	result, err := Run(context.Background(), db, gen, opts)
+	if err != nil {
+		t.Fatalf("resuming the next day: %v", err)
+	}
+	if result.Resumed != opts.Count || result.Inserted != 0 {
+		t.Fatalf("resumed from %d, inserted %d; want %d and 0", result.Resumed, result.Inserted, opts.Count)
+	}
+	cp, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !cp.CreatedUntil.Equal(want) {
+		t.Fatalf("checkpoint created_until = %v, want the first day %v", cp.CreatedUntil, want)
+	}
+
+	// An explicit date still has to match the checkpoint
+	explicit := NewGenerator(GeneratorOptions{Seed: 1, Locales: locales, CreatedWithin: 24 * time.Hour, CreatedUntil: firstDay.AddDate(0, 0, 1).Truncate(24 * time.Hour)}, "hash")
+	if _, err := Run(context.Background(), db, explicit, opts); err == nil {
+		t.Fatal("Run resumed a checkpoint of another created-until date")
+	}
+}
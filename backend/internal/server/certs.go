@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"auth-go/internal/logger"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var tlsLog = logger.For("tls")
+
+// CertReloader serves a certificate and key pair from disk and picks up
+// renewed files, e.g. from cert-manager or certbot, without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	current  atomic.Pointer[tls.Certificate]
+}
+
+// NewCertReloader loads the pair once; it fails when the files are missing
+// or do not match
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	cert, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(cert)
+	return r, nil
+}
+
+func (r *CertReloader) load() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
+
+// GetCertificate is the tls.Config hook serving the current certificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.current.Load(), nil
+}
+
+// Prepare loads the files again. Applying the returned function swaps the
+// certificate in; on error the current one stays. It fits config.ReloadHook.
+func (r *CertReloader) Prepare() (func(), error) {
+	cert, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		r.current.Store(cert)
+		tlsLog.Info("TLS certificate loaded", "subject", cert.Leaf.Subject.String(), "not_after", cert.Leaf.NotAfter)
+	}, nil
+}
+
+// NotAfter returns when the current certificate expires
+func (r *CertReloader) NotAfter() time.Time {
+	return r.current.Load().Leaf.NotAfter
+}
+
+// Watch reloads the pair whenever either file changes, until ctx is done.
+// Failed reloads, e.g. while only one of the files was renewed, are logged
+// and keep the current certificate.
+func (r *CertReloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Watch the directories: renewals replace the files instead of writing
+	// to them, and Kubernetes swaps its ..data symlink
+	for _, dir := range []string{filepath.Dir(r.certFile), filepath.Dir(r.keyFile)} {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		// Wait for both files to be written before reloading
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if r.affects(event) {
+					debounce.Reset(time.Second)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				tlsLog.Error("Watching TLS certificate files failed", "error", err)
+			case <-debounce.C:
+				apply, err := r.Prepare()
+				if err != nil {
+					tlsLog.Error("TLS certificate reload failed, keeping the current certificate", "error", err)
+					continue
+				}
+				apply()
+			}
+		}
+	}()
+	return nil
+}
+
+func (r *CertReloader) affects(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Clean(event.Name)
+	return name == filepath.Clean(r.certFile) || name == filepath.Clean(r.keyFile) || filepath.Base(name) == "..data"
+}
@@ -0,0 +1,68 @@
+// Package server configures the HTTP servers of the API: timeouts, header
+// limits and TLS with certificates reloaded from disk.
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth-go/internal/logger"
+)
+
+// Options configure New
+type Options struct {
+	Addr string
+	// ReadHeaderTimeout bounds reading the request line and headers, the
+	// defence against clients trickling headers to hold connections
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout and WriteTimeout bound reading a whole request and writing
+	// its response. Handlers may extend them, see middleware.ServerTimeoutMiddleware.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// IdleTimeout closes keep-alive connections without requests
+	IdleTimeout    time.Duration
+	MaxHeaderBytes int
+	// Certs serves TLS with certificates that can change while running
+	Certs *CertReloader
+}
+
+// New returns a server for handler. Errors of the server itself, such as
+// failed TLS handshakes, are logged through the "http" component.
+func New(handler http.Handler, opts Options) *http.Server {
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(logger.For("http").Handler(), slog.LevelWarn),
+	}
+	if opts.Certs != nil {
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: opts.Certs.GetCertificate,
+		}
+	}
+	return srv
+}
+
+// ListenAndServe serves srv over TLS when it has a TLS config, until it is
+// shut down. Unlike http.Server's, it returns nil after a shutdown.
+func ListenAndServe(srv *http.Server) error {
+	var err error
+	if srv.TLSConfig != nil {
+		// The certificate comes from TLSConfig.GetCertificate
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -3,95 +3,146 @@ package service
 import (
 	"auth-go/internal/config"
 	"auth-go/internal/domain"
+	"auth-go/internal/metrics"
 	"auth-go/pkg/utils"
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type AuthService interface {
-	Register(input *domain.RegisterInput) (*domain.User, error)
-	Login(input *domain.LoginInput) (string, *domain.User, error)
-	ForgotPassword(input *domain.ForgotPasswordInput) error
-	ResetPassword(input *domain.ResetPasswordInput) error
+	Register(ctx context.Context, input *domain.RegisterInput) (*domain.User, error)
+	Login(ctx context.Context, input *domain.LoginInput) (string, *domain.User, error)
+	ForgotPassword(ctx context.Context, input *domain.ForgotPasswordInput) error
+	ResetPassword(ctx context.Context, input *domain.ResetPasswordInput) error
 }
 
 type authService struct {
-	userRepo     domain.UserRepository
-	resetRepo    domain.PasswordResetRepository
-	emailService EmailService
-	config       *config.Config
+	userRepo  domain.UserRepository
+	txManager domain.TxManager
+	config    *config.Config
 }
 
-func NewAuthService(userRepo domain.UserRepository, resetRepo domain.PasswordResetRepository, emailService EmailService, config *config.Config) AuthService {
-	return &authService{userRepo, resetRepo, emailService, config}
+func NewAuthService(userRepo domain.UserRepository, txManager domain.TxManager, config *config.Config) AuthService {
+	return tracedAuthService{&authService{userRepo, txManager, config}}
 }
 
-func (s *authService) Register(input *domain.RegisterInput) (*domain.User, error) {
+func (s *authService) Register(ctx context.Context, input *domain.RegisterInput) (*domain.User, error) {
+	user, err := s.register(ctx, input)
+	switch {
+	case err == nil:
+		metrics.Registrations.WithLabelValues(metrics.ResultSuccess, "").Inc()
+	case errors.Is(err, domain.ErrEmailTaken):
+		metrics.Registrations.WithLabelValues(metrics.ResultFailure, "email_taken").Inc()
+	default:
+		metrics.Registrations.WithLabelValues(metrics.ResultFailure, "error").Inc()
+	}
+	return user, err
+}
+
+func (s *authService) register(ctx context.Context, input *domain.RegisterInput) (*domain.User, error) {
 	// Check if user exists
-	existingUser, _ := s.userRepo.FindByEmail(input.Email)
-	if existingUser != nil {
-		return nil, errors.New("email already registered")
+	_, err := s.userRepo.FindByEmail(ctx, input.Email)
+	if err == nil {
+		return nil, domain.ErrEmailTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
 	}
 
 	// Hash password
-	hashedPassword, err := utils.HashPassword(input.Password)
+	hashedPassword, err := hashPassword(ctx, input.Password)
 	if err != nil {
 		return nil, err
 	}
 
+	locale := input.Locale
+	if locale == "" {
+		locale = s.config.EmailDefaultLocale
+	}
+
 	newUser := &domain.User{
 		Name:     input.Name,
 		Email:    input.Email,
 		Password: hashedPassword,
+		Locale:   locale,
 	}
 
-	savedUser, err := s.userRepo.Save(newUser)
+	// Welcome email is delivered by the outbox worker
+	welcome, err := domain.NewOutboxMessage(domain.OutboxKindWelcomeEmail, domain.WelcomeEmailPayload{
+		Email:  newUser.Email,
+		Name:   newUser.Name,
+		Locale: newUser.Locale,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Send welcome email (async to avoid blocking)
-	go s.emailService.SendWelcomeEmail(savedUser.Email, savedUser.Name)
+	err = s.txManager.WithinTx(ctx, func(tx domain.Repos) error {
+		if _, err := tx.Users.Save(ctx, newUser); err != nil {
+			return err
+		}
+		return tx.Outbox.Enqueue(ctx, welcome)
+	})
+	// A concurrent registration may take the email after the check above
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, domain.ErrEmailTaken
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return savedUser, nil
+	return newUser, nil
 }
 
-func (s *authService) Login(input *domain.LoginInput) (string, *domain.User, error) {
+func (s *authService) Login(ctx context.Context, input *domain.LoginInput) (string, *domain.User, error) {
 	// Find user
-	user, err := s.userRepo.FindByEmail(input.Email)
+	user, err := s.userRepo.FindByEmail(ctx, input.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, errors.New("invalid email or password")
+			metrics.Logins.WithLabelValues(metrics.ResultFailure, "unknown_email").Inc()
+			return "", nil, domain.ErrInvalidCredentials
 		}
+		metrics.Logins.WithLabelValues(metrics.ResultFailure, "error").Inc()
 		return "", nil, err
 	}
 
 	// Check password
-	if !utils.CheckPasswordHash(input.Password, user.Password) {
-		return "", nil, errors.New("invalid email or password")
+	if !checkPassword(ctx, input.Password, user.Password) {
+		metrics.Logins.WithLabelValues(metrics.ResultFailure, "wrong_password").Inc()
+		return "", nil, domain.ErrInvalidCredentials
 	}
 
 	// Generate JWT
 	token, err := utils.GenerateToken(user.ID, user.Email, s.config.JWTSecret, s.config.JWTExpiredIn)
 	if err != nil {
+		metrics.Logins.WithLabelValues(metrics.ResultFailure, "error").Inc()
 		return "", nil, err
 	}
 
+	metrics.Logins.WithLabelValues(metrics.ResultSuccess, "").Inc()
 	return token, user, nil
 }
 
-func (s *authService) ForgotPassword(input *domain.ForgotPasswordInput) error {
-	user, err := s.userRepo.FindByEmail(input.Email)
+func (s *authService) ForgotPassword(ctx context.Context, input *domain.ForgotPasswordInput) error {
+	user, err := s.userRepo.FindByEmail(ctx, input.Email)
 	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.PasswordResetRequests.WithLabelValues(metrics.ResultFailure).Inc()
+			return err
+		}
 		// Return nil to avoid email enumeration
+		metrics.PasswordResetRequests.WithLabelValues("unknown_email").Inc()
 		return nil
 	}
 
 	// Generate token (simple random string for now, better to use crypto rand)
-	resetToken, _ := utils.HashPassword(time.Now().String() + user.Email) // Simple hack for unique string
+	resetToken, _ := hashPassword(ctx, time.Now().String()+user.Email) // Simple hack for unique string
 
 	// Save token
 	resetData := &domain.PasswordResetToken{
@@ -100,48 +151,112 @@ func (s *authService) ForgotPassword(input *domain.ForgotPasswordInput) error {
 		ExpiresAt: time.Now().Add(1 * time.Hour),
 	}
 
-	// Remove old tokens
-	s.resetRepo.DeleteByEmail(user.Email)
-
-	_, err = s.resetRepo.Save(resetData)
+	// Email is delivered by the outbox worker
+	resetLink := resetPasswordLink(s.config, resetToken, user.Email)
+	resetEmail, err := domain.NewOutboxMessage(domain.OutboxKindResetPasswordEmail, domain.ResetPasswordEmailPayload{
+		Email:     user.Email,
+		ResetLink: resetLink,
+		Locale:    user.Locale,
+	})
 	if err != nil {
+		metrics.PasswordResetRequests.WithLabelValues(metrics.ResultFailure).Inc()
 		return err
 	}
 
-	// Send email
-	resetLink := fmt.Sprintf("http://localhost:5173/reset-password?token=%s&email=%s", resetToken, user.Email)
-	go s.emailService.SendResetPasswordEmail(user.Email, resetLink)
-
-	return nil
-}
-
-func (s *authService) ResetPassword(input *domain.ResetPasswordInput) error {
-	// Validate token
-	resetData, err := s.resetRepo.FindByToken(input.Token)
+	// Remove old tokens, save the new one and enqueue the email atomically
+	var replaced int64
+	err = s.txManager.WithinTx(ctx, func(tx domain.Repos) error {
+		var err error
+		if replaced, err = tx.PasswordResets.DeleteByEmail(ctx, user.Email); err != nil {
+			return err
+		}
+		if _, err := tx.PasswordResets.Save(ctx, resetData); err != nil {
+			return err
+		}
+		return tx.Outbox.Enqueue(ctx, resetEmail)
+	})
 	if err != nil {
-		return errors.New("invalid or expired token")
+		metrics.PasswordResetRequests.WithLabelValues(metrics.ResultFailure).Inc()
+		return err
 	}
+	metrics.PasswordResetRequests.WithLabelValues(metrics.ResultSuccess).Inc()
+	metrics.TokenRevocations.WithLabelValues("replaced").Add(float64(replaced))
+	return nil
+}
 
-	if time.Now().After(resetData.ExpiresAt) {
-		return errors.New("token expired")
+func (s *authService) ResetPassword(ctx context.Context, input *domain.ResetPasswordInput) error {
+	err := s.resetPassword(ctx, input)
+	switch {
+	case err == nil:
+		metrics.PasswordResets.WithLabelValues(metrics.ResultSuccess, "").Inc()
+	case errors.Is(err, domain.ErrInvalidResetToken):
+		metrics.PasswordResets.WithLabelValues(metrics.ResultFailure, "invalid_token").Inc()
+	case errors.Is(err, domain.ErrResetTokenExpired):
+		metrics.PasswordResets.WithLabelValues(metrics.ResultFailure, "expired_token").Inc()
+	default:
+		metrics.PasswordResets.WithLabelValues(metrics.ResultFailure, "error").Inc()
 	}
+	return err
+}
 
-	// Update user password
-	user, err := s.userRepo.FindByEmail(resetData.Email)
+func (s *authService) resetPassword(ctx context.Context, input *domain.ResetPasswordInput) error {
+	// Hash before the transaction, bcrypt is slow
+	hashedPassword, err := hashPassword(ctx, input.Password)
 	if err != nil {
-		return errors.New("user not found")
+		return err
 	}
 
-	hashedPassword, _ := utils.HashPassword(input.Password)
-	user.Password = hashedPassword
+	// Consume the token, change the password and invalidate the other tokens
+	// of the user together. Deleting the token inside the transaction lets
+	// only one of several concurrent requests with the same token succeed.
+	var revoked int64
+	err = s.txManager.WithinTx(ctx, func(tx domain.Repos) error {
+		resetData, err := tx.PasswordResets.FindByToken(ctx, input.Token)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidResetToken
+			}
+			return err
+		}
+		if time.Now().After(resetData.ExpiresAt) {
+			return domain.ErrResetTokenExpired
+		}
+
+		user, err := tx.Users.FindByEmail(ctx, resetData.Email)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrUserNotFound
+			}
+			return err
+		}
 
-	_, err = s.userRepo.Update(user)
+		consumed, err := tx.PasswordResets.DeleteByToken(ctx, input.Token)
+		if err != nil {
+			return err
+		}
+		if consumed == 0 {
+			// Another request used the token first
+			return domain.ErrInvalidResetToken
+		}
+
+		user.Password = hashedPassword
+		if _, err := tx.Users.Update(ctx, user); err != nil {
+			return err
+		}
+		others, err := tx.PasswordResets.DeleteByEmail(ctx, user.Email)
+		revoked = consumed + others
+		return err
+	})
 	if err != nil {
 		return err
 	}
-
-	// Delete used token
-	s.resetRepo.DeleteByEmail(user.Email)
-
+	metrics.TokenRevocations.WithLabelValues("used").Add(float64(revoked))
 	return nil
 }
+
+// resetPasswordLink points to the frontend page that consumes a password
+// reset token, also used to let invited users choose their first password
+func resetPasswordLink(cfg *config.Config, token string, email string) string {
+	query := url.Values{"token": {token}, "email": {email}}
+	return fmt.Sprintf("%s/reset-password?%s", strings.TrimRight(cfg.AppURL, "/"), query.Encode())
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-go/internal/config"
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+	"auth-go/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func newTestAuthService(db *gorm.DB) AuthService {
+	cfg := &config.Config{
+		JWTSecret:          "test-secret-with-enough-length",
+		JWTExpiredIn:       "1h",
+		AppURL:             "http://localhost:5173",
+		EmailDefaultLocale: "en",
+	}
+	return NewAuthService(repository.NewUserRepository(db), repository.NewTxManager(db), cfg)
+}
+
+// lookupUserRepo replaces FindByEmail, to act out what concurrent requests
+// or a failing database would make it return
+type lookupUserRepo struct {
+	domain.UserRepository
+	err error
+}
+
+func (r lookupUserRepo) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return nil, r.err
+}
+
+func TestAuthServiceRegisterEmailTaken(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenTest(t)
+	auth := newTestAuthService(db)
+	input := &domain.RegisterInput{Name: "Ana", Email: "ana@example.com", Password: "password123"}
+	if _, err := auth.Register(ctx, input); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	if _, err := auth.Register(ctx, input); !errors.Is(err, domain.ErrEmailTaken) {
+		t.Fatalf("registering the email again = %v, want ErrEmailTaken", err)
+	}
+
+	// The email is taken between the lookup and the insert
+	racing := NewAuthService(lookupUserRepo{repository.NewUserRepository(db), gorm.ErrRecordNotFound}, repository.NewTxManager(db), &config.Config{})
+	if _, err := racing.Register(ctx, input); !errors.Is(err, domain.ErrEmailTaken) {
+		t.Fatalf("Register losing the race = %v, want ErrEmailTaken", err)
+	}
+}
+
+func TestAuthServiceRegisterLookupError(t *testing.T) {
+	failure := errors.New("connection refused")
+	db := database.OpenTest(t)
+	auth := NewAuthService(lookupUserRepo{repository.NewUserRepository(db), failure}, repository.NewTxManager(db), &config.Config{})
+
+	_, err := auth.Register(context.Background(), &domain.RegisterInput{Name: "Ana", Email: "ana@example.com", Password: "password123"})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Register = %v, want the lookup error", err)
+	}
+}
+
+// requestReset registers email and returns the reset token sent to it
+func requestReset(t *testing.T, db *gorm.DB, auth AuthService, email string) string {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := auth.Register(ctx, &domain.RegisterInput{Name: "Ana", Email: email, Password: "password123"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := auth.ForgotPassword(ctx, &domain.ForgotPasswordInput{Email: email}); err != nil {
+		t.Fatalf("ForgotPassword: %v", err)
+	}
+	var reset domain.PasswordResetToken
+	if err := db.Where("email = ?", email).First(&reset).Error; err != nil {
+		t.Fatalf("reading the reset token: %v", err)
+	}
+	return reset.Token
+}
+
+func TestAuthServiceResetPasswordConsumesToken(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenTest(t)
+	auth := newTestAuthService(db)
+	token := requestReset(t, db, auth, "ana@example.com")
+
+	if err := auth.ResetPassword(ctx, &domain.ResetPasswordInput{Token: token, Password: "new-password"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, _, err := auth.Login(ctx, &domain.LoginInput{Email: "ana@example.com", Password: "new-password"}); err != nil {
+		t.Fatalf("Login with the new password: %v", err)
+	}
+
+	err := auth.ResetPassword(ctx, &domain.ResetPasswordInput{Token: token, Password: "other-password"})
+	if !errors.Is(err, domain.ErrInvalidResetToken) {
+		t.Fatalf("reusing the token = %v, want ErrInvalidResetToken", err)
+	}
+}
+
+func TestAuthServiceResetPasswordExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenTest(t)
+	auth := newTestAuthService(db)
+	token := requestReset(t, db, auth, "ana@example.com")
+	if err := db.Model(&domain.PasswordResetToken{}).Where("token = ?", token).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	err := auth.ResetPassword(ctx, &domain.ResetPasswordInput{Token: token, Password: "new-password"})
+	if !errors.Is(err, domain.ErrResetTokenExpired) {
+		t.Fatalf("ResetPassword = %v, want ErrResetTokenExpired", err)
+	}
+	if _, _, err := auth.Login(ctx, &domain.LoginInput{Email: "ana@example.com", Password: "password123"}); err != nil {
+		t.Fatalf("the password changed with an expired token: %v", err)
+	}
+}
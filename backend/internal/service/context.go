@@ -0,0 +1,12 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// isContextError reports whether err comes from a cancelled or timed out
+// request, which must reach the handler instead of being masked.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -2,42 +2,87 @@ package service
 
 import (
 	"auth-go/internal/config"
-	"fmt"
+	"auth-go/internal/mail"
+	"auth-go/internal/metrics"
+	"auth-go/internal/tracing"
+	"context"
+	"time"
 
-	"gopkg.in/gomail.v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EmailService interface {
-	SendWelcomeEmail(toEmail string, name string) error
-	SendResetPasswordEmail(toEmail string, resetLink string) error
+	SendWelcomeEmail(ctx context.Context, toEmail string, name string, locale string) error
+	SendResetPasswordEmail(ctx context.Context, toEmail string, resetLink string, locale string) error
+	SendInvitationEmail(ctx context.Context, toEmail string, name string, inviteLink string, locale string) error
 }
 
 type emailService struct {
-	cfg *config.Config
+	cfg       *config.Config
+	renderer  *mail.Renderer
+	transport mail.Transport
 }
 
-func NewEmailService(cfg *config.Config) EmailService {
-	return &emailService{cfg}
+func NewEmailService(cfg *config.Config, renderer *mail.Renderer, transport mail.Transport) EmailService {
+	return &emailService{cfg, renderer, transport}
 }
 
-func (s *emailService) SendWelcomeEmail(toEmail string, name string) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", s.cfg.SMTPEmail)
-	m.SetHeader("To", toEmail)
-	m.SetHeader("Subject", "Welcome to Auth Go!")
-	m.SetBody("text/html", fmt.Sprintf("<h1>Hello %s!</h1><p>Welcome to our platform. We are glad to have you.</p>", name))
+func (s *emailService) SendWelcomeEmail(ctx context.Context, toEmail string, name string, locale string) error {
+	return s.send(ctx, toEmail, "welcome", locale, map[string]string{
+		"Name": name,
+	})
+}
+
+func (s *emailService) SendResetPasswordEmail(ctx context.Context, toEmail string, resetLink string, locale string) error {
+	return s.send(ctx, toEmail, "reset_password", locale, map[string]string{
+		"ResetLink": resetLink,
+	})
+}
 
-	d := gomail.NewDialer(s.cfg.SMTPHost, s.cfg.SMTPPort, s.cfg.SMTPEmail, s.cfg.SMTPPassword)
-	return d.DialAndSend(m)
+func (s *emailService) SendInvitationEmail(ctx context.Context, toEmail string, name string, inviteLink string, locale string) error {
+	return s.send(ctx, toEmail, "invitation", locale, map[string]string{
+		"Name":       name,
+		"InviteLink": inviteLink,
+	})
 }
 
-func (s *emailService) SendResetPasswordEmail(toEmail string, resetLink string) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", s.cfg.SMTPEmail)
-	m.SetHeader("To", toEmail)
-	m.SetHeader("Subject", "Reset Your Password")
-	m.SetBody("text/html", fmt.Sprintf("<p>Click <a href='%s'>here</a> to reset your password.</p>", resetLink))
+func (s *emailService) send(ctx context.Context, toEmail string, templateName string, locale string, data interface{}) error {
+	ctx, span := tracer.Start(ctx, "email.send", trace.WithAttributes(
+		attribute.String("email.template", templateName),
+		attribute.String("email.locale", locale),
+	))
+	start := time.Now()
+	err := s.deliver(ctx, toEmail, templateName, locale, data)
+	tracing.End(span, err)
+	result := metrics.ResultSuccess
+	if err != nil {
+		result = metrics.ResultFailure
+	}
+	metrics.EmailSends.WithLabelValues(templateName, result).Inc()
+	metrics.EmailSendDuration.WithLabelValues(templateName).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (s *emailService) deliver(ctx context.Context, toEmail string, templateName string, locale string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, span := tracer.Start(ctx, "email.render")
+	rendered, err := s.renderer.Render(templateName, locale, data)
+	tracing.End(span, err)
+	if err != nil {
+		return err
+	}
+
+	from := s.cfg.MailFrom
+	if from == "" {
+		from = s.cfg.SMTPEmail
+	}
 
-	d := gomail.NewDialer(s.cfg.SMTPHost, s.cfg.SMTPPort, s.cfg.SMTPEmail, s.cfg.SMTPPassword)
-	return d.DialAndSend(m)
+	_, span = tracer.Start(ctx, "email.transport", trace.WithSpanKind(trace.SpanKindClient))
+	err = s.transport.Send(mail.NewMessage(from, toEmail, rendered))
+	tracing.End(span, err)
+	return err
 }
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"auth-go/internal/config"
+	"auth-go/internal/domain"
+	"auth-go/internal/logger"
+	"auth-go/internal/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var outboxLog = logger.For("outbox")
+
+// OutboxHandlerFunc delivers the payload of a single outbox message
+type OutboxHandlerFunc func(ctx context.Context, payload []byte) error
+
+type OutboxWorker interface {
+	Start()
+	// Shutdown stops claiming new messages and waits for in-flight ones to finish
+	Shutdown(ctx context.Context) error
+	// Stats counts the messages by status
+	Stats(ctx context.Context) (map[string]int64, error)
+}
+
+type outboxWorker struct {
+	// ctx is used for all database and delivery calls. It is not tied to
+	// shutdown so that in-flight messages can finish while draining.
+	ctx      context.Context
+	repo     domain.OutboxRepository
+	handlers map[string]OutboxHandlerFunc
+
+	workers      int
+	batchSize    int
+	maxAttempts  int
+	pollInterval time.Duration
+	lease        time.Duration
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	// retention is how long sent messages are kept before being purged
+	retention     time.Duration
+	purgeInterval time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewOutboxWorker(repo domain.OutboxRepository, emailService EmailService, cfg *config.Config) OutboxWorker {
+	w := &outboxWorker{
+		ctx:           context.Background(),
+		repo:          repo,
+		workers:       positiveOr(cfg.OutboxWorkers, 2),
+		batchSize:     positiveOr(cfg.OutboxBatchSize, 10),
+		maxAttempts:   positiveOr(cfg.OutboxMaxAttempts, 8),
+		pollInterval:  parseDurationOr(cfg.OutboxPollInterval, 2*time.Second),
+		lease:         5 * time.Minute,
+		baseBackoff:   10 * time.Second,
+		maxBackoff:    1 * time.Hour,
+		retention:     parseDurationOr(cfg.OutboxRetention, 7*24*time.Hour),
+		purgeInterval: time.Hour,
+		stop:          make(chan struct{}),
+	}
+
+	w.handlers = map[string]OutboxHandlerFunc{
+		domain.OutboxKindWelcomeEmail: func(ctx context.Context, payload []byte) error {
+			var p domain.WelcomeEmailPayload
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return err
+			}
+			return emailService.SendWelcomeEmail(ctx, p.Email, p.Name, p.Locale)
+		},
+		domain.OutboxKindResetPasswordEmail: func(ctx context.Context, payload []byte) error {
+			var p domain.ResetPasswordEmailPayload
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return err
+			}
+			return emailService.SendResetPasswordEmail(ctx, p.Email, p.ResetLink, p.Locale)
+		},
+		domain.OutboxKindInvitationEmail: func(ctx context.Context, payload []byte) error {
+			var p domain.InvitationEmailPayload
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return err
+			}
+			return emailService.SendInvitationEmail(ctx, p.Email, p.Name, p.InviteLink, p.Locale)
+		},
+	}
+
+	return w
+}
+
+func (w *outboxWorker) Start() {
+	if stats, err := w.repo.CountByStatus(w.ctx); err == nil && stats[domain.OutboxStatusFailed] > 0 {
+		outboxLog.Warn("Outbox messages have permanently failed and need attention", "messages", stats[domain.OutboxStatusFailed])
+	}
+
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	w.wg.Add(1)
+	go w.purge()
+	outboxLog.Info("Outbox worker started", "workers", w.workers)
+}
+
+func (w *outboxWorker) Shutdown(ctx context.Context) error {
+	close(w.stop)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		outboxLog.Info("Outbox worker drained")
+		return nil
+	case <-ctx.Done():
+		// Unfinished messages keep their lease and are retried once it expires.
+		return fmt.Errorf("outbox worker did not drain in time: %w", ctx.Err())
+	}
+}
+
+func (w *outboxWorker) Stats(ctx context.Context) (map[string]int64, error) {
+	return w.repo.CountByStatus(ctx)
+}
+
+func (w *outboxWorker) run() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+
+		messages, err := w.repo.Claim(w.ctx, w.batchSize, w.lease)
+		if err != nil {
+			outboxLog.Error("Claiming outbox messages failed", "error", err)
+		}
+
+		// Finish the whole claimed batch even when stopping, so nothing is left
+		// locked until its lease expires.
+		for _, m := range messages {
+			w.process(m)
+		}
+
+		if len(messages) == 0 {
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(w.pollInterval):
+			}
+		}
+	}
+}
+
+// purge deletes sent messages older than the retention, once at start and
+// then every purgeInterval
+func (w *outboxWorker) purge() {
+	defer w.wg.Done()
+
+	for {
+		deleted, err := w.repo.PurgeSent(w.ctx, time.Now().Add(-w.retention))
+		if err != nil {
+			outboxLog.Error("Purging sent outbox messages failed", "error", err)
+		} else if deleted > 0 {
+			outboxLog.Info("Sent outbox messages purged", "messages", deleted, "retention", w.retention)
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-time.After(w.purgeInterval):
+		}
+	}
+}
+
+func (w *outboxWorker) process(m *domain.OutboxMessage) {
+	attempts := m.Attempts + 1
+
+	// Each delivery is a trace of its own, the request that enqueued the
+	// message has long finished
+	ctx, span := tracer.Start(w.ctx, "outbox.process", trace.WithAttributes(
+		attribute.String("outbox.kind", m.Kind),
+		attribute.Int64("outbox.message_id", int64(m.ID)),
+		attribute.Int("outbox.attempt", attempts),
+	))
+	var err error
+	defer func() { tracing.End(span, err) }()
+
+	handler, ok := w.handlers[m.Kind]
+	if !ok {
+		err = errors.New("unknown message kind")
+		outboxLog.ErrorContext(ctx, "Outbox message has an unknown kind, giving up", "message_id", m.ID, "kind", m.Kind)
+		w.record(w.repo.MarkFailed(ctx, m.ID, attempts, err.Error()))
+		return
+	}
+
+	err = handler(ctx, []byte(m.Payload))
+	if err == nil {
+		w.record(w.repo.MarkSent(ctx, m.ID))
+		return
+	}
+
+	if attempts >= w.maxAttempts {
+		outboxLog.ErrorContext(ctx, "Outbox message failed permanently", "message_id", m.ID, "kind", m.Kind, "attempts", attempts, "error", err)
+		w.record(w.repo.MarkFailed(ctx, m.ID, attempts, err.Error()))
+		return
+	}
+
+	next := time.Now().Add(w.backoff(attempts))
+	outboxLog.WarnContext(ctx, "Outbox message failed, retrying", "message_id", m.ID, "kind", m.Kind, "attempt", attempts, "retry_at", next, "error", err)
+	w.record(w.repo.MarkRetry(ctx, m.ID, attempts, err.Error(), next))
+}
+
+// backoff returns an exponential delay with up to 20% jitter
+func (w *outboxWorker) backoff(attempts int) time.Duration {
+	delay := w.baseBackoff << uint(attempts-1)
+	if delay <= 0 || delay > w.maxBackoff {
+		delay = w.maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+func (w *outboxWorker) record(err error) {
+	if err != nil {
+		outboxLog.Error("Updating outbox message status failed", "error", err)
+	}
+}
+
+func positiveOr(value int, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func parseDurationOr(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"auth-go/internal/domain"
+	"auth-go/internal/tracing"
+	"auth-go/pkg/utils"
+	"context"
+	"io"
+)
+
+var tracer = tracing.Tracer("service")
+
+// traced runs fn in a span named after the service method, e.g.
+// "AuthService.Login", recording the error it returns
+func traced[T any](ctx context.Context, name string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracer.Start(ctx, name)
+	result, err := fn(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+// tracedErr is traced for methods that only return an error
+func tracedErr(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	_, err := traced(ctx, name, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// hashPassword and checkPassword time bcrypt, which dominates the duration
+// of logins and registrations by design
+func hashPassword(ctx context.Context, password string) (string, error) {
+	return traced(ctx, "bcrypt.hash", func(ctx context.Context) (string, error) {
+		return utils.HashPassword(password)
+	})
+}
+
+func checkPassword(ctx context.Context, password string, hash string) bool {
+	_, span := tracer.Start(ctx, "bcrypt.compare")
+	defer span.End()
+	return utils.CheckPasswordHash(password, hash)
+}
+
+// The traced* types wrap each service so every method call gets a span
+
+type tracedAuthService struct{ next AuthService }
+
+func (t tracedAuthService) Register(ctx context.Context, input *domain.RegisterInput) (*domain.User, error) {
+	return traced(ctx, "AuthService.Register", func(ctx context.Context) (*domain.User, error) {
+		return t.next.Register(ctx, input)
+	})
+}
+
+func (t tracedAuthService) Login(ctx context.Context, input *domain.LoginInput) (string, *domain.User, error) {
+	var user *domain.User
+	token, err := traced(ctx, "AuthService.Login", func(ctx context.Context) (string, error) {
+		var token string
+		var err error
+		token, user, err = t.next.Login(ctx, input)
+		return token, err
+	})
+	return token, user, err
+}
+
+func (t tracedAuthService) ForgotPassword(ctx context.Context, input *domain.ForgotPasswordInput) error {
+	return tracedErr(ctx, "AuthService.ForgotPassword", func(ctx context.Context) error {
+		return t.next.ForgotPassword(ctx, input)
+	})
+}
+
+func (t tracedAuthService) ResetPassword(ctx context.Context, input *domain.ResetPasswordInput) error {
+	return tracedErr(ctx, "AuthService.ResetPassword", func(ctx context.Context) error {
+		return t.next.ResetPassword(ctx, input)
+	})
+}
+
+type tracedUserService struct{ next UserService }
+
+func (t tracedUserService) GetProfile(ctx context.Context, userID uint64) (*domain.User, error) {
+	return traced(ctx, "UserService.GetProfile", func(ctx context.Context) (*domain.User, error) {
+		return t.next.GetProfile(ctx, userID)
+	})
+}
+
+func (t tracedUserService) ListUsers(ctx context.Context, params domain.UserListParams) (*domain.UserListResult, error) {
+	return traced(ctx, "UserService.ListUsers", func(ctx context.Context) (*domain.UserListResult, error) {
+		return t.next.ListUsers(ctx, params)
+	})
+}
+
+type tracedUserImportService struct{ next UserImportService }
+
+func (t tracedUserImportService) Import(ctx context.Context, r io.Reader, opts domain.UserImportOptions) (*domain.UserImportReport, error) {
+	return traced(ctx, "UserImportService.Import", func(ctx context.Context) (*domain.UserImportReport, error) {
+		return t.next.Import(ctx, r, opts)
+	})
+}
+
+type tracedUserExportService struct{ UserExportService }
+
+func (t tracedUserExportService) Export(ctx context.Context, w io.Writer, params domain.UserExportParams) (int64, error) {
+	return traced(ctx, "UserExportService.Export", func(ctx context.Context) (int64, error) {
+		return t.UserExportService.Export(ctx, w, params)
+	})
+}
+
+func (t tracedUserExportService) Count(ctx context.Context, params domain.UserExportParams) (int64, error) {
+	return traced(ctx, "UserExportService.Count", func(ctx context.Context) (int64, error) {
+		return t.UserExportService.Count(ctx, params)
+	})
+}
+
+func (t tracedUserExportService) StartJob(ctx context.Context, requestedBy uint64, params domain.UserExportParams) (*domain.ExportJob, error) {
+	return traced(ctx, "UserExportService.StartJob", func(ctx context.Context) (*domain.ExportJob, error) {
+		return t.UserExportService.StartJob(ctx, requestedBy, params)
+	})
+}
+
+func (t tracedUserExportService) GetJob(ctx context.Context, requestedBy uint64, id string) (*domain.ExportJob, error) {
+	return traced(ctx, "UserExportService.GetJob", func(ctx context.Context) (*domain.ExportJob, error) {
+		return t.UserExportService.GetJob(ctx, requestedBy, id)
+	})
+}
+
+func (t tracedUserExportService) OpenJob(ctx context.Context, requestedBy uint64, id string) (*domain.ExportJob, string, error) {
+	var path string
+	job, err := traced(ctx, "UserExportService.OpenJob", func(ctx context.Context) (*domain.ExportJob, error) {
+		var job *domain.ExportJob
+		var err error
+		job, path, err = t.UserExportService.OpenJob(ctx, requestedBy, id)
+		return job, err
+	})
+	return job, path, err
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"auth-go/internal/config"
+	"auth-go/internal/domain"
+	"auth-go/internal/export"
+	"auth-go/internal/logger"
+	"auth-go/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var exportLog = logger.For("export")
+
+// exportBatchSize is the number of users read per query while exporting
+const exportBatchSize = 1000
+
+type UserExportService interface {
+	// Export writes the matching users to w and returns the number of rows
+	Export(ctx context.Context, w io.Writer, params domain.UserExportParams) (int64, error)
+	// Count validates params and returns the number of rows an export would have
+	Count(ctx context.Context, params domain.UserExportParams) (int64, error)
+	// StartJob exports to a file in the background
+	StartJob(ctx context.Context, requestedBy uint64, params domain.UserExportParams) (*domain.ExportJob, error)
+	GetJob(ctx context.Context, requestedBy uint64, id string) (*domain.ExportJob, error)
+	// OpenJob returns a finished job along with the path of its file
+	OpenJob(ctx context.Context, requestedBy uint64, id string) (*domain.ExportJob, string, error)
+
+	// Start fails jobs interrupted by a restart and begins removing expired files
+	Start()
+	// Shutdown cancels running jobs and waits for them to stop
+	Shutdown(ctx context.Context) error
+}
+
+type userExportService struct {
+	userRepo domain.UserRepository
+	jobRepo  domain.ExportJobRepository
+
+	dir string
+	ttl time.Duration
+
+	// ctx is cancelled on shutdown, stopping running jobs
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewUserExportService(userRepo domain.UserRepository, jobRepo domain.ExportJobRepository, cfg *config.Config) UserExportService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return tracedUserExportService{&userExportService{
+		userRepo: userRepo,
+		jobRepo:  jobRepo,
+		dir:      cfg.ExportDir,
+		ttl:      parseDurationOr(cfg.ExportTTL, 24*time.Hour),
+		ctx:      ctx,
+		cancel:   cancel,
+		slots:    make(chan struct{}, positiveOr(cfg.ExportMaxJobs, 2)),
+	}}
+}
+
+// exportPlan is a validated export request
+type exportPlan struct {
+	format  string
+	columns []string
+	query   domain.UserListQuery
+}
+
+func (s *userExportService) plan(params domain.UserExportParams) (*exportPlan, error) {
+	plan := &exportPlan{format: params.Format}
+	switch plan.format {
+	case "":
+		plan.format = export.FormatCSV
+	case export.FormatCSV, export.FormatJSONL, export.FormatXLSX:
+	default:
+		return nil, fmt.Errorf("%w: format must be one of csv, jsonl, xlsx", domain.ErrInvalidExport)
+	}
+
+	plan.columns = domain.UserExportColumns
+	if params.Columns != "" {
+		plan.columns = nil
+		for _, column := range strings.Split(params.Columns, ",") {
+			column = strings.TrimSpace(column)
+			if !isExportColumn(column) {
+				return nil, fmt.Errorf("%w: unknown column %q, expected any of %s", domain.ErrInvalidExport, column, strings.Join(domain.UserExportColumns, ", "))
+			}
+			plan.columns = append(plan.columns, column)
+		}
+	}
+
+	searchMode, err := parseSearchMode(params.Filters.SearchMode)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseUserFilter(params.Filters)
+	if err != nil {
+		return nil, err
+	}
+	plan.query = domain.UserListQuery{
+		Search:     strings.TrimSpace(params.Filters.Search),
+		SearchMode: searchMode,
+		Filter:     filter,
+	}
+	return plan, nil
+}
+
+func (s *userExportService) Count(ctx context.Context, params domain.UserExportParams) (int64, error) {
+	plan, err := s.plan(params)
+	if err != nil {
+		return 0, err
+	}
+	return s.userRepo.Count(ctx, plan.query)
+}
+
+func (s *userExportService) Export(ctx context.Context, w io.Writer, params domain.UserExportParams) (int64, error) {
+	plan, err := s.plan(params)
+	if err != nil {
+		return 0, err
+	}
+	return s.write(ctx, w, plan)
+}
+
+func (s *userExportService) write(ctx context.Context, w io.Writer, plan *exportPlan) (int64, error) {
+	writer, err := export.NewWriter(plan.format, w)
+	if err != nil {
+		return 0, err
+	}
+	if err := writer.WriteHeader(plan.columns); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	values := make([]interface{}, len(plan.columns))
+	err = s.userRepo.Stream(ctx, plan.query, exportBatchSize, func(users []*domain.User) error {
+		for _, user := range users {
+			for i, column := range plan.columns {
+				values[i] = exportValue(user, column)
+			}
+			if err := writer.WriteRow(values); err != nil {
+				return err
+			}
+			rows++
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+func (s *userExportService) StartJob(ctx context.Context, requestedBy uint64, params domain.UserExportParams) (*domain.ExportJob, error) {
+	plan, err := s.plan(params)
+	if err != nil {
+		return nil, err
+	}
+	params.Format = plan.format
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	id, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &domain.ExportJob{
+		ID:          id,
+		RequestedBy: requestedBy,
+		Format:      plan.format,
+		Params:      string(encoded),
+		Status:      domain.ExportJobPending,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.run(*job, plan)
+	return job, nil
+}
+
+// run executes a job once a slot is free. Jobs that are still waiting at
+// shutdown are failed, like jobs interrupted while running.
+func (s *userExportService) run(job domain.ExportJob, plan *exportPlan) {
+	defer s.wg.Done()
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-s.ctx.Done():
+		s.finish(&job, 0, s.ctx.Err())
+		return
+	}
+
+	job.Status = domain.ExportJobRunning
+	if err := s.jobRepo.Update(s.ctx, &job); err != nil {
+		s.finish(&job, 0, err)
+		return
+	}
+
+	rows, err := s.writeFile(&job, plan)
+	s.finish(&job, rows, err)
+}
+
+// writeFile writes to a temporary file first, so a job's file only ever
+// exists complete
+func (s *userExportService) writeFile(job *domain.ExportJob, plan *exportPlan) (int64, error) {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return 0, err
+	}
+	path := filepath.Join(s.dir, job.ID+"."+job.Format)
+	tmp, err := os.CreateTemp(s.dir, job.ID+"-*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	rows, err := s.write(s.ctx, tmp, plan)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return rows, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return rows, err
+	}
+	job.FilePath = path
+	return rows, nil
+}
+
+func (s *userExportService) finish(job *domain.ExportJob, rows int64, err error) {
+	now := time.Now()
+	job.RowCount = rows
+	job.CompletedAt = &now
+	if err != nil {
+		exportLog.Error("Export job failed", "job_id", job.ID, "error", err)
+		job.Status = domain.ExportJobFailed
+		job.Error = err.Error()
+		if errors.Is(err, context.Canceled) {
+			job.Error = "interrupted by a shutdown"
+		}
+	} else {
+		job.Status = domain.ExportJobDone
+		// The download window starts when the file is ready
+		job.ExpiresAt = now.Add(s.ttl)
+	}
+
+	// The job context may be cancelled already, record the outcome anyway
+	if err := s.jobRepo.Update(context.Background(), job); err != nil {
+		exportLog.Error("Updating export job failed", "job_id", job.ID, "error", err)
+	}
+}
+
+func (s *userExportService) GetJob(ctx context.Context, requestedBy uint64, id string) (*domain.ExportJob, error) {
+	job, err := s.jobRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrExportJobNotFound
+		}
+		return nil, err
+	}
+	if job.RequestedBy != requestedBy || time.Now().After(job.ExpiresAt) {
+		return nil, domain.ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+func (s *userExportService) OpenJob(ctx context.Context, requestedBy uint64, id string) (*domain.ExportJob, string, error) {
+	job, err := s.GetJob(ctx, requestedBy, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Status != domain.ExportJobDone {
+		return nil, "", domain.ErrExportJobNotReady
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		// Written by another instance without a shared EXPORT_DIR, or removed
+		return nil, "", domain.ErrExportJobNotFound
+	}
+	return job, job.FilePath, nil
+}
+
+func (s *userExportService) Start() {
+	if n, err := s.jobRepo.FailUnfinished(s.ctx, "interrupted by a restart"); err != nil {
+		exportLog.Error("Cleaning up unfinished export jobs failed", "error", err)
+	} else if n > 0 {
+		exportLog.Warn("Marked unfinished export jobs as failed", "jobs", n)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for {
+			s.removeExpired()
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *userExportService) removeExpired() {
+	jobs, err := s.jobRepo.FindExpired(s.ctx, time.Now(), 100)
+	if err != nil {
+		if !isContextError(err) {
+			exportLog.Error("Finding expired export jobs failed", "error", err)
+		}
+		return
+	}
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				exportLog.Error("Removing export file failed", "file", job.FilePath, "error", err)
+				continue
+			}
+		}
+		if err := s.jobRepo.Delete(s.ctx, job.ID); err != nil {
+			exportLog.Error("Deleting export job failed", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+func (s *userExportService) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("export jobs did not stop in time: %w", ctx.Err())
+	}
+}
+
+func isExportColumn(column string) bool {
+	for _, c := range domain.UserExportColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func exportValue(user *domain.User, column string) interface{} {
+	switch column {
+	case "id":
+		return user.ID
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "locale":
+		return user.Locale
+	case "role":
+		return user.Role
+	case "status":
+		return user.Status
+	case "organization":
+		return user.Organization
+	case "email_verified_at":
+		return user.EmailVerifiedAt
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,580 @@
+package service
+
+import (
+	"auth-go/internal/config"
+	"auth-go/internal/domain"
+	"auth-go/internal/i18n"
+	"auth-go/internal/logger"
+	"auth-go/pkg/utils"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var importLog = logger.For("import")
+
+// importBatchSize is the number of rows looked up and written per transaction
+const importBatchSize = 500
+
+// inviteTTL is how long the set-password link of an invitation stays valid
+const inviteTTL = 7 * 24 * time.Hour
+
+type UserImportService interface {
+	// Import streams users from r. The returned report covers the rows
+	// processed so far even when an error aborts the import.
+	Import(ctx context.Context, r io.Reader, opts domain.UserImportOptions) (*domain.UserImportReport, error)
+}
+
+type userImportService struct {
+	userRepo  domain.UserRepository
+	txManager domain.TxManager
+	config    *config.Config
+}
+
+func NewUserImportService(userRepo domain.UserRepository, txManager domain.TxManager, config *config.Config) UserImportService {
+	return tracedUserImportService{&userImportService{userRepo, txManager, config}}
+}
+
+// importRow is a row on its way through the import
+type importRow struct {
+	line     int
+	record   domain.UserImportRecord
+	errors   []domain.UserImportFieldError
+	existing *domain.User
+	hash     string
+}
+
+func (r *importRow) fail(field string, message string) {
+	r.errors = append(r.errors, domain.UserImportFieldError{Field: field, Message: message})
+}
+
+func (s *userImportService) Import(ctx context.Context, r io.Reader, opts domain.UserImportOptions) (*domain.UserImportReport, error) {
+	if opts.Mode == "" {
+		opts.Mode = domain.ImportModeInsert
+	}
+	switch opts.Mode {
+	case domain.ImportModeInsert, domain.ImportModeSkip, domain.ImportModeUpsert:
+	default:
+		return nil, fmt.Errorf("%w: mode must be one of insert, skip, upsert", domain.ErrInvalidImport)
+	}
+
+	rows, err := newImportReader(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.UserImportReport{
+		Format: opts.Format,
+		Mode:   opts.Mode,
+		DryRun: opts.DryRun,
+		Errors: []domain.UserImportRowError{},
+	}
+
+	// Emails seen so far, to reject duplicates within the file
+	seen := make(map[string]int)
+	loc := i18n.For(ctx)
+	batch := make([]*importRow, 0, importBatchSize)
+	for {
+		row, err := rows.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		report.Total++
+		if len(row.errors) == 0 {
+			s.validate(loc, row, opts, seen)
+		}
+		batch = append(batch, row)
+
+		if len(batch) == importBatchSize {
+			if err := s.importBatch(ctx, batch, opts, report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.importBatch(ctx, batch, opts, report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// validate applies the RegisterInput rules to a row. The password rules are
+// skipped for rows carrying a legacy hash, or no password at all when the
+// user is invited to choose one. Messages are in the language of loc.
+func (s *userImportService) validate(loc *i18n.Localizer, row *importRow, opts domain.UserImportOptions, seen map[string]int) {
+	rec := &row.record
+	input := domain.RegisterInput{Name: rec.Name, Email: rec.Email, Password: rec.Password, Locale: rec.Locale}
+	engine := binding.Validator.Engine().(*validator.Validate)
+
+	var err error
+	switch {
+	case rec.Password != "" && rec.PasswordHash != "":
+		row.fail("password_hash", loc.Text("import.password_and_hash", "set either password or password_hash, not both"))
+		err = engine.StructExcept(input, "Password")
+	case rec.PasswordHash != "":
+		if _, costErr := bcrypt.Cost([]byte(rec.PasswordHash)); costErr != nil {
+			row.fail("password_hash", loc.Text("import.password_hash", "must be a bcrypt hash"))
+		}
+		err = engine.StructExcept(input, "Password")
+	case rec.Password == "" && opts.Invite:
+		err = engine.StructExcept(input, "Password")
+	default:
+		err = engine.Struct(input)
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, fe := range validationErrors {
+			row.fail(registerInputField(fe.StructField()), loc.Rule(fe.Tag(), fe.Param()))
+		}
+	} else if err != nil {
+		row.fail("", err.Error())
+	}
+
+	switch rec.Role {
+	case "", domain.RoleUser, domain.RoleAdmin:
+	default:
+		row.fail("role", loc.Rule("oneof", "user, admin"))
+	}
+
+	if len(row.errors) > 0 {
+		return
+	}
+	key := strings.ToLower(rec.Email)
+	if first, ok := seen[key]; ok {
+		row.fail("email", loc.Text("import.duplicate_email", "duplicates the email on row {row}", "row", strconv.Itoa(first)))
+		return
+	}
+	seen[key] = row.line
+}
+
+func (s *userImportService) importBatch(ctx context.Context, batch []*importRow, opts domain.UserImportOptions, report *domain.UserImportReport) error {
+	creates, updates, skipped, err := s.classify(ctx, batch, opts)
+	if err != nil {
+		return err
+	}
+
+	if !opts.DryRun {
+		err := s.write(ctx, creates, updates, opts)
+		// Emails registered since the lookup fail the whole batch; classify
+		// again so they are reported as taken and the other rows are saved
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if creates, updates, skipped, err = s.classify(ctx, batch, opts); err != nil {
+				return err
+			}
+			err = s.write(ctx, creates, updates, opts)
+		}
+		if err != nil {
+			if isContextError(err) {
+				return err
+			}
+			// The batch was rolled back as a whole. Driver errors stay in the
+			// log, where the request ID finds them.
+			importLog.ErrorContext(ctx, "Saving an import batch failed", "rows", len(creates)+len(updates), "error", err)
+			message := i18n.For(ctx).Text("import.save_failed", "could not be saved, quote the request ID when reporting it")
+			for _, row := range append(creates, updates...) {
+				row.fail("", message)
+			}
+			creates, updates = nil, nil
+		}
+	}
+
+	report.Skipped += skipped
+	report.Created += len(creates)
+	report.Updated += len(updates)
+	if opts.Invite {
+		report.Invited += len(creates)
+	}
+	for _, row := range batch {
+		if len(row.errors) == 0 {
+			continue
+		}
+		report.Failed++
+		if opts.MaxErrors > 0 && len(report.Errors) >= opts.MaxErrors {
+			report.ErrorsTruncated = true
+			continue
+		}
+		report.Errors = append(report.Errors, domain.UserImportRowError{
+			Row:    row.line,
+			Email:  row.record.Email,
+			Errors: row.errors,
+		})
+	}
+
+	return nil
+}
+
+// classify looks up the emails of the valid rows of a batch and sorts them
+// into users to create, users to update and skipped rows, failing the rows of
+// taken emails in insert mode
+func (s *userImportService) classify(ctx context.Context, batch []*importRow, opts domain.UserImportOptions) ([]*importRow, []*importRow, int, error) {
+	var emails []string
+	for _, row := range batch {
+		if len(row.errors) == 0 {
+			emails = append(emails, row.record.Email)
+		}
+	}
+
+	existing, err := s.userRepo.FindByEmails(ctx, emails)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	byEmail := make(map[string]*domain.User, len(existing))
+	for _, user := range existing {
+		byEmail[strings.ToLower(user.Email)] = user
+	}
+
+	var creates, updates []*importRow
+	skipped := 0
+	for _, row := range batch {
+		if len(row.errors) > 0 {
+			continue
+		}
+		user := byEmail[strings.ToLower(row.record.Email)]
+		switch {
+		case user == nil:
+			creates = append(creates, row)
+		case opts.Mode == domain.ImportModeSkip:
+			skipped++
+		case opts.Mode == domain.ImportModeUpsert:
+			row.existing = user
+			updates = append(updates, row)
+		default:
+			row.fail("email", i18n.For(ctx).Text("errors.email_taken", domain.ErrEmailTaken.Message))
+		}
+	}
+	return creates, updates, skipped, nil
+}
+
+// write saves one batch and its invitations in a single transaction
+func (s *userImportService) write(ctx context.Context, creates []*importRow, updates []*importRow, opts domain.UserImportOptions) error {
+	if err := hashImportPasswords(ctx, append(creates, updates...)); err != nil {
+		return err
+	}
+
+	var users []*domain.User
+	var tokens []*domain.PasswordResetToken
+	var invites []*domain.OutboxMessage
+	for _, row := range creates {
+		rec := row.record
+		user := &domain.User{
+			Name:     rec.Name,
+			Email:    rec.Email,
+			Password: row.hash,
+			Locale:   rec.Locale,
+			Role:     rec.Role,
+			Status:   domain.StatusActive,
+		}
+		if user.Password == "" {
+			// Not a bcrypt hash, so no password matches until one is set
+			// through the invitation link
+			unusable, err := utils.RandomToken(16)
+			if err != nil {
+				return err
+			}
+			user.Password = "!" + unusable
+		}
+		if user.Locale == "" {
+			user.Locale = s.config.EmailDefaultLocale
+		}
+		if user.Role == "" {
+			user.Role = domain.RoleUser
+		}
+		users = append(users, user)
+
+		if !opts.Invite {
+			continue
+		}
+		token, err := utils.RandomToken(32)
+		if err != nil {
+			return err
+		}
+		tokens = append(tokens, &domain.PasswordResetToken{
+			Email:     user.Email,
+			Token:     token,
+			ExpiresAt: time.Now().Add(inviteTTL),
+		})
+		invite, err := domain.NewOutboxMessage(domain.OutboxKindInvitationEmail, domain.InvitationEmailPayload{
+			Email:      user.Email,
+			Name:       user.Name,
+			InviteLink: resetPasswordLink(s.config, token, user.Email),
+			Locale:     user.Locale,
+		})
+		if err != nil {
+			return err
+		}
+		invites = append(invites, invite)
+	}
+
+	for _, row := range updates {
+		rec := row.record
+		row.existing.Name = rec.Name
+		if rec.Locale != "" {
+			row.existing.Locale = rec.Locale
+		}
+		if rec.Role != "" {
+			row.existing.Role = rec.Role
+		}
+		if row.hash != "" {
+			row.existing.Password = row.hash
+		}
+	}
+
+	return s.txManager.WithinTx(ctx, func(tx domain.Repos) error {
+		for _, user := range users {
+			if _, err := tx.Users.Save(ctx, user); err != nil {
+				return err
+			}
+		}
+		for _, row := range updates {
+			if _, err := tx.Users.Update(ctx, row.existing); err != nil {
+				return err
+			}
+		}
+		for _, token := range tokens {
+			if _, err := tx.PasswordResets.Save(ctx, token); err != nil {
+				return err
+			}
+		}
+		if len(invites) == 0 {
+			return nil
+		}
+		return tx.Outbox.Enqueue(ctx, invites...)
+	})
+}
+
+// hashImportPasswords sets row.hash for every row, hashing plain passwords
+// on all CPUs since bcrypt dominates the cost of an import
+func hashImportPasswords(ctx context.Context, rows []*importRow) error {
+	jobs := make(chan *importRow)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range jobs {
+				hash, err := utils.HashPassword(row.record.Password)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				row.hash = hash
+			}
+		}()
+	}
+
+	var err error
+	for _, row := range rows {
+		// Hashed already when a batch is written again
+		if row.hash != "" {
+			continue
+		}
+		if row.record.PasswordHash != "" {
+			row.hash = row.record.PasswordHash
+			continue
+		}
+		if row.record.Password == "" {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		jobs <- row
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// registerInputField maps a RegisterInput field to its JSON name
+func registerInputField(structField string) string {
+	field, ok := reflect.TypeOf(domain.RegisterInput{}).FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	return strings.Split(field.Tag.Get("json"), ",")[0]
+}
+
+// importReader yields the rows of an import file in order
+type importReader interface {
+	next() (*importRow, error)
+}
+
+func newImportReader(r io.Reader, format string) (importReader, error) {
+	switch format {
+	case domain.ImportFormatCSV:
+		return newCSVImportReader(r)
+	case domain.ImportFormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &jsonlImportReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("%w: format must be one of csv, jsonl", domain.ErrInvalidImport)
+	}
+}
+
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns []string
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", domain.ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: unreadable header: %v", domain.ErrInvalidImport, err)
+	}
+
+	known := make(map[string]bool)
+	recordType := reflect.TypeOf(domain.UserImportRecord{})
+	for i := 0; i < recordType.NumField(); i++ {
+		known[recordType.Field(i).Tag.Get("json")] = true
+	}
+
+	columns := make([]string, len(header))
+	present := make(map[string]bool)
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheet exports often start with a byte order mark
+			name = strings.TrimPrefix(name, "\uFEFF")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("%w: unknown column %q", domain.ErrInvalidImport, name)
+		}
+		if present[name] {
+			return nil, fmt.Errorf("%w: duplicate column %q", domain.ErrInvalidImport, name)
+		}
+		present[name] = true
+		columns[i] = name
+	}
+	if !present["name"] || !present["email"] {
+		return nil, fmt.Errorf("%w: the name and email columns are required", domain.ErrInvalidImport)
+	}
+
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) next() (*importRow, error) {
+	fields, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		row := &importRow{line: parseErr.StartLine}
+		row.fail("", parseErr.Err.Error())
+		return row, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	row := &importRow{line: line}
+	for i, value := range fields {
+		setImportField(&row.record, r.columns[i], value)
+	}
+	return row, nil
+}
+
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlImportReader) next() (*importRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &importRow{line: r.line}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.record); err != nil {
+			row.fail("", fmt.Sprintf("invalid JSON: %v", err))
+			return row, nil
+		}
+		normalizeImportRecord(&row.record)
+		return row, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func setImportField(rec *domain.UserImportRecord, column string, value string) {
+	// Passwords are taken verbatim, other fields are trimmed
+	if column != "password" {
+		value = strings.TrimSpace(value)
+	}
+	switch column {
+	case "name":
+		rec.Name = value
+	case "email":
+		rec.Email = value
+	case "password":
+		rec.Password = value
+	case "password_hash":
+		rec.PasswordHash = value
+	case "locale":
+		rec.Locale = value
+	case "role":
+		rec.Role = value
+	}
+}
+
+func normalizeImportRecord(rec *domain.UserImportRecord) {
+	rec.Name = strings.TrimSpace(rec.Name)
+	rec.Email = strings.TrimSpace(rec.Email)
+	rec.PasswordHash = strings.TrimSpace(rec.PasswordHash)
+	rec.Locale = strings.TrimSpace(rec.Locale)
+	rec.Role = strings.TrimSpace(rec.Role)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"auth-go/internal/config"
+	"auth-go/internal/database"
+	"auth-go/internal/domain"
+	"auth-go/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// staleUserRepo misses the users saved after it was created on the first
+// FindByEmails, as when they register while an import is running
+type staleUserRepo struct {
+	domain.UserRepository
+	lookups int
+}
+
+func (r *staleUserRepo) FindByEmails(ctx context.Context, emails []string) ([]*domain.User, error) {
+	r.lookups++
+	if r.lookups == 1 {
+		return nil, nil
+	}
+	return r.UserRepository.FindByEmails(ctx, emails)
+}
+
+func TestUserImportServiceEmailTakenDuringImport(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenTest(t)
+	users := repository.NewUserRepository(db)
+	if _, err := users.Save(ctx, &domain.User{Name: "Taken", Email: "taken@example.com", Password: "hash"}); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csv := fmt.Sprintf("name,email,password_hash\nAna,ana@example.com,%s\nBudi,taken@example.com,%s\n", hash, hash)
+	imports := NewUserImportService(&staleUserRepo{UserRepository: users}, repository.NewTxManager(db), &config.Config{})
+	report, err := imports.Import(ctx, strings.NewReader(csv), domain.UserImportOptions{Format: domain.ImportFormatCSV})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	if report.Created != 1 || report.Failed != 1 {
+		t.Fatalf("created %d, failed %d; want 1 and 1", report.Created, report.Failed)
+	}
+	rowErr := report.Errors[0]
+	if rowErr.Email != "taken@example.com" || len(rowErr.Errors) != 1 || rowErr.Errors[0].Field != "email" || rowErr.Errors[0].Message != domain.ErrEmailTaken.Message {
+		t.Fatalf("row error = %+v, want email taken", rowErr)
+	}
+	if _, err := users.FindByEmail(ctx, "ana@example.com"); err != nil {
+		t.Fatalf("the other row was not saved: %v", err)
+	}
+}
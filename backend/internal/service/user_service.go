@@ -1,43 +1,289 @@
 package service
 
 import (
+	"auth-go/internal/config"
 	"auth-go/internal/domain"
+	"auth-go/pkg/utils"
+	"context"
 	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
 )
 
 type UserService interface {
-	GetProfile(userID uint64) (*domain.User, error)
-	GetAllUsers(page int, limit int, search string) ([]*domain.User, int64, error)
+	GetProfile(ctx context.Context, userID uint64) (*domain.User, error)
+	ListUsers(ctx context.Context, params domain.UserListParams) (*domain.UserListResult, error)
 }
 
 type userService struct {
 	userRepo domain.UserRepository
+	config   *config.Config
 }
 
-func NewUserService(userRepo domain.UserRepository) UserService {
-	return &userService{userRepo}
+func NewUserService(userRepo domain.UserRepository, config *config.Config) UserService {
+	return tracedUserService{&userService{userRepo, config}}
 }
 
-func (s *userService) GetProfile(userID uint64) (*domain.User, error) {
-	user, err := s.userRepo.FindByID(userID)
+func (s *userService) GetProfile(ctx context.Context, userID uint64) (*domain.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
 	}
 	// Sanitize output just in case (e.g. remove password)
 	user.Password = ""
 	return user, nil
 }
 
-func (s *userService) GetAllUsers(page int, limit int, search string) ([]*domain.User, int64, error) {
-	users, total, err := s.userRepo.FindAll(page, limit, search)
+func (s *userService) ListUsers(ctx context.Context, params domain.UserListParams) (*domain.UserListResult, error) {
+	query := domain.UserListQuery{
+		Search:     strings.TrimSpace(params.Search),
+		SearchMode: params.SearchMode,
+		Sort:       domain.UserSortCreatedAt,
+		Limit:      params.Limit,
+		Count:      params.Count,
+	}
+
+	if params.Sort != "" {
+		query.Sort = strings.TrimPrefix(params.Sort, "-")
+		query.Desc = strings.HasPrefix(params.Sort, "-")
+	} else if query.Search != "" {
+		query.Sort = domain.UserSortRelevance
+	}
+	switch query.Sort {
+	case domain.UserSortCreatedAt, domain.UserSortName, domain.UserSortEmail:
+	case domain.UserSortRelevance:
+		if query.Search == "" || query.Desc {
+			return nil, fmt.Errorf("%w: sort by relevance requires a search and cannot be descending", domain.ErrInvalidListQuery)
+		}
+	default:
+		return nil, fmt.Errorf("%w: sort must be one of created_at, name, email, relevance (prefix with - for descending)", domain.ErrInvalidListQuery)
+	}
+
+	searchMode, err := parseSearchMode(params.SearchMode)
+	if err != nil {
+		return nil, err
+	}
+	query.SearchMode = searchMode
+
+	filter, err := parseUserFilter(params)
+	if err != nil {
+		return nil, err
+	}
+	query.Filter = filter
+
+	if query.Limit <= 0 {
+		query.Limit = 10
+	}
+	if query.Limit > 100 {
+		query.Limit = 100
+	}
+
+	// Clients compute page numbers from the total, so it stays exact unless
+	// ?count=approx opts in
+	switch query.Count {
+	case "":
+		query.Count = domain.CountExact
+	case domain.CountExact, domain.CountApprox, domain.CountNone:
+	default:
+		return nil, fmt.Errorf("%w: count must be one of exact, approx, none", domain.ErrInvalidListQuery)
+	}
+
+	if params.Cursor != "" {
+		var cursor domain.UserCursor
+		if err := utils.DecodeCursor(params.Cursor, s.cursorSecret(), &cursor); err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		// A cursor only makes sense for the ordering it was issued for
+		if cursor.Sort != query.Sort || cursor.Desc != query.Desc {
+			return nil, domain.ErrInvalidCursor
+		}
+		query.Cursor = &cursor
+	} else if params.Page > 1 {
+		query.Offset = (params.Page - 1) * query.Limit
+	}
+
+	page, err := s.userRepo.List(ctx, query)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	// Sanitize passwords
-	for _, user := range users {
+	terms := strings.Fields(query.Search)
+	for _, user := range page.Users {
 		user.Password = ""
+		user.Highlight = highlightUser(user, terms)
+	}
+
+	result := &domain.UserListResult{
+		Users:       page.Users,
+		Total:       page.Total,
+		TotalApprox: page.TotalApprox,
+		SearchMode:  page.SearchMode,
+		Sort:        params.Sort,
+		Limit:       query.Limit,
+	}
+	if query.Cursor == nil && params.Page > 1 {
+		result.Page = params.Page
+	}
+	if result.Sort == "" {
+		result.Sort = query.Sort
 	}
+	if page.Next != nil {
+		if result.Next, err = utils.EncodeCursor(page.Next, s.cursorSecret()); err != nil {
+			return nil, err
+		}
+	}
+	if page.Prev != nil {
+		if result.Prev, err = utils.EncodeCursor(page.Prev, s.cursorSecret()); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func parseSearchMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return domain.SearchAuto, nil
+	case domain.SearchAuto, domain.SearchPrefix, domain.SearchFullText, domain.SearchFuzzy:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: search_mode must be one of auto, prefix, fulltext, fuzzy", domain.ErrInvalidListQuery)
+	}
+}
 
-	return users, total, nil
+// parseUserFilter validates the filter query parameters. Dates are RFC 3339
+// timestamps or plain days; a plain created_to day includes the whole day.
+func parseUserFilter(params domain.UserListParams) (domain.UserFilter, error) {
+	filter := domain.UserFilter{Status: params.Status, Role: params.Role}
+
+	switch filter.Status {
+	case "", domain.StatusActive, domain.StatusSuspended:
+	default:
+		return filter, fmt.Errorf("%w: status must be one of active, suspended", domain.ErrInvalidListQuery)
+	}
+	switch filter.Role {
+	case "", domain.RoleUser, domain.RoleAdmin:
+	default:
+		return filter, fmt.Errorf("%w: role must be one of user, admin", domain.ErrInvalidListQuery)
+	}
+
+	if params.Verified != "" {
+		verified, err := strconv.ParseBool(params.Verified)
+		if err != nil {
+			return filter, fmt.Errorf("%w: verified must be true or false", domain.ErrInvalidListQuery)
+		}
+		filter.Verified = &verified
+	}
+
+	if params.CreatedFrom != "" {
+		from, _, err := parseFilterTime(params.CreatedFrom)
+		if err != nil {
+			return filter, fmt.Errorf("%w: created_from must be a date (2006-01-02) or RFC 3339 timestamp", domain.ErrInvalidListQuery)
+		}
+		filter.CreatedFrom = &from
+	}
+	if params.CreatedTo != "" {
+		to, dateOnly, err := parseFilterTime(params.CreatedTo)
+		if err != nil {
+			return filter, fmt.Errorf("%w: created_to must be a date (2006-01-02) or RFC 3339 timestamp", domain.ErrInvalidListQuery)
+		}
+		if dateOnly {
+			to = to.Add(24 * time.Hour)
+		}
+		filter.CreatedTo = &to
+	}
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, fmt.Errorf("%w: created_from must be before created_to", domain.ErrInvalidListQuery)
+	}
+
+	return filter, nil
+}
+
+func parseFilterTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// highlightUser wraps the search terms found in the user's name and email in
+// <mark> tags. The rest of the text is HTML escaped so the result can be
+// rendered as is; fields without a match are left out.
+func highlightUser(user *domain.User, terms []string) map[string]string {
+	if len(terms) == 0 {
+		return nil
+	}
+	highlight := make(map[string]string)
+	if marked, ok := highlightText(user.Name, terms); ok {
+		highlight["name"] = marked
+	}
+	if marked, ok := highlightText(user.Email, terms); ok {
+		highlight["email"] = marked
+	}
+	if len(highlight) == 0 {
+		return nil
+	}
+	return highlight
+}
+
+func highlightText(text string, terms []string) (string, bool) {
+	// Mark matched byte ranges on the lowercased text, which keeps offsets
+	// aligned as long as lowercasing does not change the encoded length
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) || !utf8.ValidString(text) {
+		return html.EscapeString(text), false
+	}
+	marked := make([]bool, len(text))
+	found := false
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		for start := 0; term != ""; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				marked[j] = true
+			}
+			found = true
+			start += i + len(term)
+		}
+	}
+	if !found {
+		return html.EscapeString(text), false
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return b.String(), true
+}
+
+func (s *userService) cursorSecret() string {
+	if s.config.CursorSecret != "" {
+		return s.config.CursorSecret
+	}
+	return s.config.JWTSecret
 }
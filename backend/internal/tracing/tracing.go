@@ -0,0 +1,161 @@
+// Package tracing sets up OpenTelemetry tracing: the exporter spans are sent
+// to, sampling, and W3C trace context propagation.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Options.Exporter
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterMemory = "memory"
+)
+
+// OTLP protocols accepted by Options.OTLPProtocol
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// Options configure Setup
+type Options struct {
+	// Exporter is "none" (the default), "otlp", "stdout" (one JSON document
+	// per span) or "memory" (kept for Provider.Spans, e.g. in tests)
+	Exporter string
+	// OTLPEndpoint is the collector's host:port, e.g. "localhost:4318". When
+	// empty the exporter's default applies, which honours the standard
+	// OTEL_EXPORTER_OTLP_* environment variables.
+	OTLPEndpoint string
+	// OTLPProtocol is "http/protobuf" (the default) or "grpc"
+	OTLPProtocol string
+	// OTLPInsecure sends spans without TLS
+	OTLPInsecure bool
+	// SampleRatio is the share of new traces recorded, between 0 and 1.
+	// Requests carrying a sampled traceparent are always recorded.
+	SampleRatio float64
+	ServiceName string
+	Environment string
+}
+
+// Provider owns the exporter spans are sent to
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	memory   *tracetest.InMemoryExporter
+}
+
+// Setup installs the global tracer provider and the W3C trace context and
+// baggage propagators. With the "none" exporter no spans are recorded.
+func Setup(ctx context.Context, opts Options) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	p := &Provider{}
+	var processor sdktrace.SpanProcessor
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return p, nil
+	case ExporterOTLP:
+		exporter, err := newOTLPExporter(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		processor = sdktrace.NewBatchSpanProcessor(exporter)
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		processor = sdktrace.NewSimpleSpanProcessor(exporter)
+	case ExporterMemory:
+		p.memory = tracetest.NewInMemoryExporter()
+		processor = sdktrace.NewSimpleSpanProcessor(p.memory)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.DeploymentEnvironmentNameKey.String(opts.Environment),
+	))
+	if err != nil {
+		return nil, err
+	}
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(p.provider)
+	return p, nil
+}
+
+func newOTLPExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	switch opts.OTLPProtocol {
+	case "", ProtocolHTTP:
+		var options []otlptracehttp.Option
+		if opts.OTLPEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, options...)
+	case ProtocolGRPC:
+		var options []otlptracegrpc.Option
+		if opts.OTLPEndpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(opts.OTLPEndpoint))
+		}
+		if opts.OTLPInsecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q", opts.OTLPProtocol)
+	}
+}
+
+// Spans returns the spans recorded by the "memory" exporter so far
+func (p *Provider) Spans() tracetest.SpanStubs {
+	if p.memory == nil {
+		return nil
+	}
+	return p.memory.GetSpans()
+}
+
+// Shutdown flushes pending spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	return p.provider.Shutdown(ctx)
+}
+
+// Tracer returns the tracer of a layer of the API, e.g. "service"
+func Tracer(layer string) trace.Tracer {
+	return otel.Tracer("auth-go/" + layer)
+}
+
+// End records err, if any, on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor serializes v into an opaque token signed with HMAC-SHA256,
+// so clients cannot forge positions in a listing.
+func EncodeCursor(v interface{}, secret string) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signCursor(encoded, secret), nil
+}
+
+// DecodeCursor verifies the signature of token and unmarshals it into v
+func DecodeCursor(token string, secret string, v interface{}) error {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return errInvalidCursor
+	}
+	if !hmac.Equal([]byte(signature), []byte(signCursor(encoded, secret))) {
+		return errInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return errInvalidCursor
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return errInvalidCursor
+	}
+	return nil
+}
+
+func signCursor(encoded string, secret string) string {
+	mac := hmac.New(sha256.New, []byte("cursor:"+secret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -52,3 +52,9 @@ func ValidateToken(signedToken string, secret string) (*JWTClaim, error) {
 
 	return claims, nil
 }
+
+// IsTokenExpired reports whether ValidateToken failed only because the
+// token expired
+func IsTokenExpired(err error) bool {
+	return errors.Is(err, jwt.ErrTokenExpired)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// OriginPattern matches the Origin header of browser requests. Its host may
+// start with "*." to match any subdomain, though not the domain itself.
+type OriginPattern struct {
+	scheme   string
+	host     string
+	port     string
+	wildcard bool
+}
+
+// ParseOriginPattern parses an origin such as https://app.example.com or a
+// pattern such as https://*.example.com:8443
+func ParseOriginPattern(pattern string) (OriginPattern, error) {
+	invalid := errors.New("origin must look like https://app.example.com or https://*.example.com, got " + pattern)
+
+	scheme, rest, ok := strings.Cut(pattern, "://")
+	if !ok || scheme == "" {
+		return OriginPattern{}, invalid
+	}
+	wildcard := strings.HasPrefix(rest, "*.")
+	rest = strings.TrimPrefix(rest, "*.")
+
+	u, err := url.Parse(scheme + "://" + rest)
+	if err != nil || u.Hostname() == "" || u.Path != "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" || strings.Contains(rest, "*") {
+		return OriginPattern{}, invalid
+	}
+	return OriginPattern{
+		scheme:   strings.ToLower(u.Scheme),
+		host:     strings.ToLower(u.Hostname()),
+		port:     u.Port(),
+		wildcard: wildcard,
+	}, nil
+}
+
+// Match reports whether origin is allowed by the pattern
+func (p OriginPattern) Match(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || strings.ToLower(u.Scheme) != p.scheme || u.Port() != p.port {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if p.wildcard {
+		return strings.HasSuffix(host, "."+p.host)
+	}
+	return host == p.host
+}